@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/svenwltr/devilctl/pkg/bll/registry"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
+)
+
+// APIServer provides a REST API to control the speakers without MQTT.
+type APIServer struct {
+	Addr     string
+	Speakers *registry.Registry
+}
+
+type APISpeaker struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Location string    `json:"location"`
+	LastSeen time.Time `json:"lastSeen"`
+	State    *APIState `json:"state,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type APIState struct {
+	PowerState     string `json:"powerState"`
+	TransportState string `json:"transportState"`
+	Volume         int    `json:"volume"`
+	Muted          bool   `json:"muted"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (s *APIServer) Run(ctx context.Context) error {
+	r := chi.NewRouter()
+	r.Get("/speakers", s.listSpeakers)
+	r.Route("/speakers/{id}", func(r chi.Router) {
+		r.Get("/", s.getSpeaker)
+		r.Put("/volume", s.speakerAction(s.setVolume))
+		r.Put("/mute", s.speakerAction(s.setMute))
+		r.Put("/power", s.speakerAction(s.setPower))
+		r.Put("/transport", s.speakerAction(s.setTransport))
+	})
+
+	server := &http.Server{
+		Addr:    s.Addr,
+		Handler: r,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logrus.Infof("serving REST API on %#v", s.Addr)
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (s *APIServer) listSpeakers(w http.ResponseWriter, r *http.Request) {
+	result := probeSpeakers(s.Speakers.List(), func(speaker raumfeld.Speaker) APISpeaker {
+		return s.newAPISpeaker(r.Context(), speaker)
+	})
+
+	respondJSON(w, http.StatusOK, result)
+}
+
+func (s *APIServer) getSpeaker(w http.ResponseWriter, r *http.Request) {
+	speaker, found := s.lookup(w, r)
+	if !found {
+		return
+	}
+
+	respondJSON(w, http.StatusOK, s.newAPISpeaker(r.Context(), speaker))
+}
+
+func (s *APIServer) speakerAction(action func(*http.Request, raumfeld.Speaker) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		speaker, found := s.lookup(w, r)
+		if !found {
+			return
+		}
+
+		err := action(r, speaker)
+		var badRequest *apiBadRequestError
+		if errors.As(err, &badRequest) {
+			respondJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			respondJSON(w, http.StatusBadGateway, apiError{Error: err.Error()})
+			return
+		}
+
+		respondJSON(w, http.StatusOK, s.newAPISpeaker(r.Context(), speaker))
+	}
+}
+
+func (s *APIServer) setVolume(r *http.Request, speaker raumfeld.Speaker) error {
+	var body struct {
+		Volume *int `json:"volume"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+
+	if body.Volume == nil || *body.Volume < 0 || *body.Volume > 100 {
+		return &apiBadRequestError{fmt.Errorf("volume must be between 0 and 100")}
+	}
+
+	return speaker.SetVolumePercent(r.Context(), uint16(*body.Volume))
+}
+
+func (s *APIServer) setMute(r *http.Request, speaker raumfeld.Speaker) error {
+	var body struct {
+		Muted *bool `json:"muted"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+
+	if body.Muted == nil {
+		return &apiBadRequestError{fmt.Errorf("muted is required")}
+	}
+
+	return speaker.SetMute(r.Context(), *body.Muted)
+}
+
+func (s *APIServer) setPower(r *http.Request, speaker raumfeld.Speaker) error {
+	var body struct {
+		On *bool `json:"on"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+
+	if body.On == nil {
+		return &apiBadRequestError{fmt.Errorf("on is required")}
+	}
+
+	return speaker.SetOnOff(r.Context(), *body.On)
+}
+
+func (s *APIServer) setTransport(r *http.Request, speaker raumfeld.Speaker) error {
+	var body struct {
+		Action string `json:"action"`
+	}
+	err := decodeJSON(r, &body)
+	if err != nil {
+		return err
+	}
+
+	err = speaker.SetTransport(r.Context(), body.Action)
+	if errors.Is(err, raumfeld.ErrUnknownTransportAction) {
+		return &apiBadRequestError{err}
+	}
+	return err
+}
+
+func (s *APIServer) lookup(w http.ResponseWriter, r *http.Request) (raumfeld.Speaker, bool) {
+	id := chi.URLParam(r, "id")
+
+	speaker, found := s.Speakers.Get(id)
+	if !found {
+		respondJSON(w, http.StatusNotFound, apiError{
+			Error: fmt.Sprintf("speaker %#v not found", id),
+		})
+	}
+
+	return speaker, found
+}
+
+func (s *APIServer) newAPISpeaker(ctx context.Context, speaker raumfeld.Speaker) APISpeaker {
+	result := APISpeaker{
+		ID:       speaker.ID(),
+		Name:     speaker.FriendlyName(),
+		Location: speaker.Location().String(),
+	}
+
+	entry, found := s.Speakers.Entry(speaker.ID())
+	if found {
+		result.LastSeen = entry.LastSeen
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	state, err := speaker.State(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.State = &APIState{
+		PowerState:     state.PowerState,
+		TransportState: state.TransportState,
+		Volume:         state.Volume,
+		Muted:          state.Muted,
+	}
+
+	return result
+}
+
+type apiBadRequestError struct {
+	err error
+}
+
+func (e *apiBadRequestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *apiBadRequestError) Unwrap() error {
+	return e.err
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil {
+		return &apiBadRequestError{fmt.Errorf("decode request body: %w", err)}
+	}
+	return nil
+}
+
+func respondJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logrus.WithError(err).Warn("failed to write response")
+	}
+}
@@ -4,46 +4,190 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/svenwltr/devilctl/pkg/bll/registry"
 	"github.com/svenwltr/devilctl/pkg/bll/ticker"
+	"github.com/svenwltr/devilctl/pkg/dal/homeassistant"
 	"github.com/svenwltr/devilctl/pkg/dal/homie"
+	"github.com/svenwltr/devilctl/pkg/dal/mqttconn"
 	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
 	"golang.org/x/sync/errgroup"
 )
 
+const (
+	ConventionHomie         = "homie"
+	ConventionHomie5        = "homie5"
+	ConventionHomeAssistant = "homeassistant"
+)
+
 type HomieBridgeRunner struct {
-	broker string
+	mqtt       mqttconn.Options
+	convention string
+	homieRoot  string
+	deviceID   string
+	deviceName string
+	locations  []string
+	host       string
+	noSSDP     bool
+	httpListen string
 }
 
 func (r *HomieBridgeRunner) Bind(cmd *cobra.Command) error {
+	bindMQTTFlags(cmd, &r.mqtt)
+	cmd.PersistentFlags().StringVar(
+		&r.convention, "convention", ConventionHomie,
+		`The MQTT convention to publish the speakers with. One of "homie", "homie5" or "homeassistant".`)
+	cmd.PersistentFlags().StringVar(
+		&r.homieRoot, "homie-root", homie.DefaultRoot,
+		`The root topic of the Homie devices.`)
 	cmd.PersistentFlags().StringVar(
-		&r.broker, "broker", "",
-		`The broker MQTT URI. ex: tcp://10.10.1.1:1883`)
+		&r.deviceID, "device-id", homie.DefaultDeviceID,
+		`The Homie device ID or the base topic for Home Assistant. Needs to be unique, if multiple bridges use the same broker.`)
+	cmd.PersistentFlags().StringVar(
+		&r.deviceName, "device-name", "",
+		`The human readable name of the device. Defaults to "devilctl <device-id>".`)
+	cmd.PersistentFlags().StringSliceVar(
+		&r.locations, "location", nil,
+		`Location of the UPnP description of a speaker. Can be specified multiple times. `+
+			`Defaults to the comma separated list in $`+EnvLocations+`. `+
+			`ex: http://10.10.1.2:54888/cd19c884-dcea-4368-bcb2-fa70d3165631.xml`)
+	cmd.PersistentFlags().StringVar(
+		&r.host, "raumfeld-host", "",
+		`URL of the Raumfeld host, which manages the zones. Gets discovered via SSDP, if empty. ex: http://10.10.1.3`)
+	cmd.PersistentFlags().BoolVar(
+		&r.noSSDP, "no-ssdp", false,
+		`Disable SSDP discovery and only use the speakers given by --location.`)
+	cmd.PersistentFlags().StringVar(
+		&r.httpListen, "http-listen", "",
+		`Address for the REST API to listen on. The API is disabled, if empty. ex: :8080`)
 	return nil
 }
 
 func (r *HomieBridgeRunner) Run(ctx context.Context) error {
-	homieBroker, err := homie.New(r.broker)
+	locations, err := ParseLocations(locationsOrEnv(r.locations))
+	if err != nil {
+		return err
+	}
+
+	if r.noSSDP && len(locations) == 0 {
+		return fmt.Errorf("SSDP discovery is disabled, but no --location is given")
+	}
+
+	broker, err := r.newBroker()
 	if err != nil {
-		return fmt.Errorf("create homie broker: %w", err)
+		return err
+	}
+	defer broker.MustClose()
+
+	deviceName := r.deviceName
+	if deviceName == "" {
+		deviceName = "devilctl " + r.deviceID
 	}
-	defer homieBroker.MustClose()
 
 	bridge := HomieBridge{
-		Broker: homieBroker,
+		Broker:      broker,
+		DeviceName:  deviceName,
+		Speakers:    registry.New(),
+		Locations:   locations,
+		DisableSSDP: r.noSSDP,
+		APIAddr:     r.httpListen,
+	}
+
+	if r.host != "" {
+		hostURL, err := url.Parse(r.host)
+		if err != nil {
+			return fmt.Errorf("parse raumfeld host %#v: %w", r.host, err)
+		}
+		bridge.Host = raumfeld.NewHost(hostURL)
 	}
 
 	return bridge.Run(ctx)
 }
 
+func (r *HomieBridgeRunner) newBroker() (Broker, error) {
+	switch r.convention {
+	case ConventionHomie:
+		broker, err := homie.New(mqttOptionsFromEnv(r.mqtt), r.homieRoot, r.deviceID)
+		if err != nil {
+			return nil, fmt.Errorf("create homie broker: %w", err)
+		}
+		return broker, nil
+
+	case ConventionHomie5:
+		broker, err := homie.NewV5(mqttOptionsFromEnv(r.mqtt), r.homieRoot, r.deviceID)
+		if err != nil {
+			return nil, fmt.Errorf("create homie 5 broker: %w", err)
+		}
+		return broker, nil
+
+	case ConventionHomeAssistant:
+		broker, err := homeassistant.New(mqttOptionsFromEnv(r.mqtt), r.deviceID)
+		if err != nil {
+			return nil, fmt.Errorf("create home assistant broker: %w", err)
+		}
+		return broker, nil
+
+	default:
+		return nil, fmt.Errorf("unknown convention %#v", r.convention)
+	}
+}
+
+// Broker publishes the bridge state to MQTT. The definitions are described
+// with Homie types, but the implementation might translate them into another
+// convention.
+type Broker interface {
+	PublishDevice(homie.Device) error
+	PublishNode(homie.Node) error
+	PublishProperty(homie.Property) error
+	PublishValue(nodeID, propertyID string, value any) error
+	SetActionHandler(func(nodeID, propertyID, value string) error)
+	SetTargetHandler(func(nodeID, propertyID string, err error))
+	SetState(state string) error
+	MustClose()
+}
+
 type HomieBridge struct {
-	Broker   *homie.Broker
-	Speakers map[string]raumfeld.Speaker
+	Broker     Broker
+	DeviceName string
+	Speakers   *registry.Registry
+
+	// Host and Zones get updated by the discovery loop and by zone actions,
+	// therefore they are guarded by zonesMu.
+	Host    *raumfeld.Host
+	Zones   raumfeld.ZoneConfig
+	zonesMu sync.RWMutex
+
+	// Locations contains speakers that are added in addition to the ones
+	// found via SSDP.
+	Locations   []*url.URL
+	DisableSSDP bool
+
+	noHostWarning sync.Once
+
+	// APIAddr enables the REST API on the given address, if not empty.
+	APIAddr string
+
+	// definitionsMu prevents concurrent publishing of the definitions,
+	// since each publish removes the nodes that are not part of it.
+	definitionsMu sync.Mutex
+
+	stateMu      sync.Mutex
+	problems     map[string]map[string]error
+	initializing int
+
+	// publishStateMu keeps the device state publishes in the order of the
+	// changes.
+	publishStateMu sync.Mutex
+
+	featuresMu sync.Mutex
+	features   map[string]speakerFeatures
 }
 
 func (b *HomieBridge) Run(ctx context.Context) error {
@@ -60,37 +204,247 @@ func (b *HomieBridge) Run(ctx context.Context) error {
 		return sub.Run(ctx)
 	})
 
+	if b.APIAddr != "" {
+		api := APIServer{
+			Addr:     b.APIAddr,
+			Speakers: b.Speakers,
+		}
+
+		group.Go(func() error {
+			return api.Run(ctx)
+		})
+	}
+
+	var speakerEvents <-chan raumfeld.SpeakerEvent
+	if !b.DisableSSDP {
+		listener := raumfeld.NewListener()
+		speakerEvents = listener.Events()
+
+		group.Go(func() error {
+			return listener.Run(ctx)
+		})
+	}
+
 	group.Go(func() error {
-		for range ticker.Every(ctx, 5*time.Minute) {
-			speakers, err := raumfeld.Discover(ctx)
+		// The listener only catches changes, therefore we still need a full
+		// resync to get the initial state and to refresh the subscriptions.
+		tick := ticker.Every(ctx, 5*time.Minute)
+		for {
+			select {
+			case _, ok := <-tick:
+				if !ok {
+					return nil
+				}
+
+				err := b.resync(ctx, sub)
+				if err != nil {
+					return err
+				}
+
+				enableHandler.Do(func() {
+					b.Broker.SetTargetHandler(b.OnTargetStatus)
+					b.Broker.SetActionHandler(b.HandleBrokerAction)
+				})
+
+			case event := <-speakerEvents:
+				err := b.handleSpeakerEvent(ctx, sub, event)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+
+	return group.Wait()
+}
+
+func (b *HomieBridge) resync(ctx context.Context, sub *raumfeld.SubscriptionServer) error {
+	speakers, err := b.discoverSpeakers(ctx)
+	if err != nil {
+		return fmt.Errorf("discover speakers: %w", err)
+	}
+	logrus.Infof("discovered %d devices", len(speakers))
+
+	events := b.Speakers.Replace(speakers)
+
+	err = b.RefreshZones(ctx)
+	if err != nil {
+		logrus.WithError(err).Warn("zones are not available")
+	}
+
+	err = b.applySpeakerEvents(ctx, sub, events)
+	if err != nil {
+		return err
+	}
+
+	published := map[string]bool{}
+	for _, event := range events {
+		published[event.ID] = event.Type != raumfeld.SpeakerRemoved
+	}
+
+	// Subscribing is a no-op for existing subscriptions, but retries the ones
+	// that failed previously. The same applies to the initial state, which
+	// would otherwise stay missing until the speaker changes.
+	for _, speaker := range b.Speakers.List() {
+		err := sub.Subscribe(speaker)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to subscribe to speaker %#v", speaker.ID())
+		}
+
+		if !published[speaker.ID()] && b.HasProblem(speaker.ID(), ProblemState) {
+			err := b.PublishState(ctx, speaker)
 			if err != nil {
-				return fmt.Errorf("discover speakers: %w", err)
+				return err
 			}
-			logrus.Infof("discovered %d devices", len(speakers))
-			b.Speakers = speakers
+		}
+	}
+
+	return nil
+}
+
+func (b *HomieBridge) applySpeakerEvents(ctx context.Context, sub *raumfeld.SubscriptionServer, events []raumfeld.SpeakerEvent) error {
+	// The states are queried before publishing the definitions, since they
+	// tell which optional properties the speakers support.
+	states := map[string]raumfeld.State{}
+
+	for _, event := range events {
+		logrus.Infof("speaker %#v %s", event.ID, event.Type)
 
-			enableHandler.Do(func() {
-				b.Broker.ActionHandler = b.HandleBrokerAction
-			})
+		switch event.Type {
+		case raumfeld.SpeakerRemoved:
+			sub.Unsubscribe(event.ID)
+			b.ClearProblems(event.ID)
+			b.forgetSpeakerFeatures(event.ID)
 
-			err = b.PublishHomieDefinitions(ctx)
+		case raumfeld.SpeakerAdded, raumfeld.SpeakerUpdated:
+			err := sub.Subscribe(event.Speaker)
 			if err != nil {
-				return fmt.Errorf("publish homie definitions: %w", err)
+				// The speaker might not be ready yet. The next resync retries.
+				logrus.WithError(err).Warnf("failed to subscribe to speaker %#v", event.ID)
 			}
 
-			for _, speaker := range speakers {
-				err := sub.Subscribe(speaker)
-				if err != nil {
-					return fmt.Errorf("subscribe: %w", err)
-				}
+			state, ok := b.queryState(ctx, event.Speaker)
+			if ok {
+				b.setSpeakerFeatures(event.ID, featuresOf(state))
+				states[event.ID] = state
 			}
+		}
+	}
+
+	err := b.PublishHomieDefinitions(ctx)
+	if err != nil {
+		return fmt.Errorf("publish homie definitions: %w", err)
+	}
 
+	for id, state := range states {
+		b.publishState(id, state)
+	}
+
+	return nil
+}
+
+// PublishState queries the current state of the speaker and publishes it, so
+// we do not have to wait for the first event. The definitions get published
+// again, if the speaker reports other optional properties than before.
+func (b *HomieBridge) PublishState(ctx context.Context, speaker raumfeld.Speaker) error {
+	state, ok := b.queryState(ctx, speaker)
+	if !ok {
+		return nil
+	}
+
+	if b.setSpeakerFeatures(speaker.ID(), featuresOf(state)) {
+		err := b.PublishHomieDefinitions(ctx)
+		if err != nil {
+			return fmt.Errorf("publish homie definitions: %w", err)
 		}
+	}
+
+	b.publishState(speaker.ID(), state)
+	return nil
+}
+
+// queryState queries the state of the speaker. A failure is recorded as
+// problem of the speaker.
+func (b *HomieBridge) queryState(ctx context.Context, speaker raumfeld.Speaker) (raumfeld.State, bool) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	state, err := speaker.State(ctx)
+	b.SetProblem(speaker.ID(), ProblemState, err)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to query state of speaker %#v", speaker.ID())
+		return raumfeld.State{}, false
+	}
+
+	return state, true
+}
 
+func (b *HomieBridge) publishState(id string, state raumfeld.State) {
+	b.OnVolumeChange(id, state.Volume, raumfeld.ChannelMaster)
+	for channel, volume := range state.ChannelVolumes {
+		b.OnVolumeChange(id, volume, channel)
+	}
+	b.OnMuteChange(id, state.Muted, raumfeld.ChannelMaster)
+	b.OnTransportStateChange(id, state.TransportState)
+	if state.PowerState != "" {
+		b.OnPowerStateChange(id, state.PowerState)
+	}
+	if state.Tone != nil {
+		b.OnBassChange(id, state.Tone.Bass)
+		b.OnTrebleChange(id, state.Tone.Treble)
+		b.OnBalanceChange(id, state.Tone.Balance)
+		b.OnLoudnessChange(id, state.Tone.Loudness, raumfeld.ChannelMaster)
+	}
+}
+
+func (b *HomieBridge) handleSpeakerEvent(ctx context.Context, sub *raumfeld.SubscriptionServer, event raumfeld.SpeakerEvent) error {
+	var events []raumfeld.SpeakerEvent
+
+	switch event.Type {
+	case raumfeld.SpeakerAdded, raumfeld.SpeakerUpdated:
+		events = b.Speakers.Put(event.Speaker)
+
+	case raumfeld.SpeakerRemoved:
+		events = b.Speakers.Remove(event.ID)
+	}
+
+	if len(events) == 0 {
 		return nil
-	})
+	}
 
-	return group.Wait()
+	return b.applySpeakerEvents(ctx, sub, events)
+}
+
+func (b *HomieBridge) discoverSpeakers(ctx context.Context) (map[string]raumfeld.Speaker, error) {
+	speakers := map[string]raumfeld.Speaker{}
+
+	if !b.DisableSSDP {
+		discovered, err := raumfeld.Discover(ctx)
+		if err != nil {
+			return nil, err
+		}
+		speakers = discovered
+	}
+
+	for _, location := range b.Locations {
+		// An unresponsive speaker would otherwise block the resync forever.
+		locationCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		speaker, err := raumfeld.New(locationCtx, location)
+		cancel()
+		// The speaker ID is not known without a connection, therefore the
+		// problem is recorded for the location.
+		b.SetProblem(location.String(), ProblemConnect, err)
+		if err != nil {
+			// A single unreachable speaker should not stop the bridge.
+			logrus.WithError(err).Warnf("failed to connect to %#v", location.String())
+			continue
+		}
+		logrus.Infof("connected to %#v", location.String())
+
+		speakers[speaker.ID()] = speaker
+	}
+
+	return speakers, nil
 }
 
 func (b *HomieBridge) HandleBrokerAction(nodeID, propertyID, value string) error {
@@ -99,7 +453,12 @@ func (b *HomieBridge) HandleBrokerAction(nodeID, propertyID, value string) error
 		WithField("property-id", propertyID).
 		WithField("value", value).
 		Info("received new action from broker")
-	speaker, found := b.Speakers[nodeID]
+
+	if nodeID == ZoneManagerNodeID || strings.HasPrefix(nodeID, ZoneNodePrefix) {
+		return b.HandleZoneAction(nodeID, propertyID, value)
+	}
+
+	speaker, found := b.Speakers.Get(nodeID)
 	if !found {
 		return fmt.Errorf("node %#v not found in cache", nodeID)
 	}
@@ -113,81 +472,423 @@ func (b *HomieBridge) HandleBrokerAction(nodeID, propertyID, value string) error
 
 		return speaker.SetVolumeFloat(context.Background(), vol)
 
+	case "volume-lf", "volume-rf":
+		vol, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+
+		channel := raumfeld.ChannelLF
+		if propertyID == "volume-rf" {
+			channel = raumfeld.ChannelRF
+		}
+
+		return speaker.SetChannelVolumeFloat(context.Background(), channel, vol)
+
 	case "mute":
 		return speaker.SetMute(context.Background(), value == "true")
 
 	case "onoff":
 		return speaker.SetOnOff(context.Background(), value == "true")
 
+	case "bass", "treble", "balance":
+		level, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		return b.handleToneAction(speaker, propertyID, level)
+
+	case "loudness":
+		return speaker.SetLoudness(context.Background(), value == "true")
+
+	case "transport":
+		return speaker.SetTransport(context.Background(), value)
+
+	case "seek":
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		return speaker.Seek(context.Background(), time.Duration(seconds)*time.Second)
+
 	default:
 		return fmt.Errorf("no action for property %#v", propertyID)
 
 	}
 }
 
+func (b *HomieBridge) handleToneAction(speaker raumfeld.Speaker, propertyID string, level int) error {
+	ctx := context.Background()
+
+	switch propertyID {
+	case "bass":
+		return speaker.SetBass(ctx, level)
+	case "treble":
+		return speaker.SetTreble(ctx, level)
+	default:
+		return speaker.SetBalance(ctx, level)
+	}
+}
+
 func (b *HomieBridge) PublishHomieDefinitions(ctx context.Context) error {
 	logrus.Infof("publishing homie nodes")
 
+	b.definitionsMu.Lock()
+	defer b.definitionsMu.Unlock()
+
+	endInit := b.beginInit()
+	defer endInit()
+
 	device := homie.Device{
-		Name:           "devilctl raumfeld-bridge",
+		Name:           b.DeviceName,
 		Implementation: "github.com/svenwltr/devilctl",
 	}
 
-	for nodeID, speaker := range b.Speakers {
+	for _, speaker := range b.Speakers.List() {
+		nodeID := speaker.ID()
+		properties := speakerProperties(nodeID, b.speakerFeatures(nodeID))
+
+		node := homie.Node{
+			NodeID: nodeID,
+			Name:   speaker.FriendlyName(),
+			Type:   "Speaker",
+		}
+		for _, property := range properties {
+			node.PropertyIDs = append(node.PropertyIDs, property.PropertyID)
+		}
+
+		errs := []error{b.Broker.PublishNode(node)}
+		for _, property := range properties {
+			errs = append(errs, b.Broker.PublishProperty(property))
+		}
+
+		err := errors.Join(errs...)
+		if err != nil {
+			return err
+		}
+
 		device.NodeIDs = append(device.NodeIDs, nodeID)
-		err := errors.Join(
-			b.Broker.PublishNode(homie.Node{
-				NodeID:      nodeID,
-				Name:        speaker.FriendlyName(),
-				Type:        "Speaker",
-				PropertyIDs: []string{"onoff", "volume", "mute"},
-			}),
-			b.Broker.PublishProperty(homie.Property{
+	}
+
+	zoneNodeIDs, err := b.PublishZoneDefinitions()
+	if err != nil {
+		return err
+	}
+	device.NodeIDs = append(device.NodeIDs, zoneNodeIDs...)
+
+	return b.Broker.PublishDevice(device)
+}
+
+// speakerFeatures contains the optional properties, that a speaker reported
+// with its last queried state.
+type speakerFeatures struct {
+	VolumeLF bool
+	VolumeRF bool
+	Tone     bool
+}
+
+func featuresOf(state raumfeld.State) speakerFeatures {
+	_, volumeLF := state.ChannelVolumes[raumfeld.ChannelLF]
+	_, volumeRF := state.ChannelVolumes[raumfeld.ChannelRF]
+
+	return speakerFeatures{
+		VolumeLF: volumeLF,
+		VolumeRF: volumeRF,
+		Tone:     state.Tone != nil,
+	}
+}
+
+func (b *HomieBridge) speakerFeatures(speakerID string) speakerFeatures {
+	b.featuresMu.Lock()
+	defer b.featuresMu.Unlock()
+
+	return b.features[speakerID]
+}
+
+// setSpeakerFeatures records the features of a speaker and returns whether
+// they changed.
+func (b *HomieBridge) setSpeakerFeatures(speakerID string, features speakerFeatures) bool {
+	b.featuresMu.Lock()
+	defer b.featuresMu.Unlock()
+
+	if b.features == nil {
+		b.features = map[string]speakerFeatures{}
+	}
+
+	changed := b.features[speakerID] != features
+	b.features[speakerID] = features
+	return changed
+}
+
+func (b *HomieBridge) forgetSpeakerFeatures(speakerID string) {
+	b.featuresMu.Lock()
+	defer b.featuresMu.Unlock()
+
+	delete(b.features, speakerID)
+}
+
+// speakerProperties returns the properties of a speaker node. The optional
+// properties are only included, if the speaker supports them.
+func speakerProperties(nodeID string, features speakerFeatures) []homie.Property {
+	properties := []homie.Property{
+		{
+			NodeID:     nodeID,
+			PropertyID: "onoff",
+			Name:       "On/Off",
+			DataType:   homie.DataTypeBoolean,
+			Retained:   true,
+			Settable:   true,
+		},
+		{
+			NodeID:     nodeID,
+			PropertyID: "power-state",
+			Name:       "Power State",
+			DataType:   homie.DataTypeEnum,
+			Format: strings.Join([]string{
+				raumfeld.PowerStateActive,
+				raumfeld.PowerStateAutomaticStandby,
+				raumfeld.PowerStateManualStandby,
+			}, ","),
+			Retained: true,
+		},
+		{
+			NodeID:     nodeID,
+			PropertyID: "volume",
+			Name:       "Volume",
+			DataType:   homie.DataTypeFloat,
+			Format:     "0:1",
+			Retained:   true,
+			Settable:   true,
+		},
+	}
+
+	if features.VolumeLF {
+		properties = append(properties, homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "volume-lf",
+			Name:       "Volume Left",
+			DataType:   homie.DataTypeFloat,
+			Format:     "0:1",
+			Retained:   true,
+			Settable:   true,
+		})
+	}
+
+	if features.VolumeRF {
+		properties = append(properties, homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "volume-rf",
+			Name:       "Volume Right",
+			DataType:   homie.DataTypeFloat,
+			Format:     "0:1",
+			Retained:   true,
+			Settable:   true,
+		})
+	}
+
+	properties = append(properties, homie.Property{
+		NodeID:     nodeID,
+		PropertyID: "mute",
+		Name:       "Mute",
+		DataType:   homie.DataTypeBoolean,
+		Retained:   true,
+		Settable:   true,
+	})
+
+	if features.Tone {
+		properties = append(properties,
+			homie.Property{
 				NodeID:     nodeID,
-				PropertyID: "onoff",
-				Name:       "On/Off",
-				DataType:   "boolean",
+				PropertyID: "bass",
+				Name:       "Bass",
+				DataType:   homie.DataTypeInteger,
+				Format:     fmt.Sprintf("%d:%d", raumfeld.MinTone, raumfeld.MaxTone),
 				Retained:   true,
 				Settable:   true,
-			}),
-			b.Broker.PublishProperty(homie.Property{
+			},
+			homie.Property{
 				NodeID:     nodeID,
-				PropertyID: "volume",
-				Name:       "Volume",
-				DataType:   "float",
-				Format:     "0:1",
+				PropertyID: "treble",
+				Name:       "Treble",
+				DataType:   homie.DataTypeInteger,
+				Format:     fmt.Sprintf("%d:%d", raumfeld.MinTone, raumfeld.MaxTone),
 				Retained:   true,
 				Settable:   true,
-			}),
-			b.Broker.PublishProperty(homie.Property{
+			},
+			homie.Property{
 				NodeID:     nodeID,
-				PropertyID: "mute",
-				Name:       "Mute",
-				DataType:   "boolean",
-				Format:     "0:1",
+				PropertyID: "balance",
+				Name:       "Balance",
+				DataType:   homie.DataTypeInteger,
+				Format:     fmt.Sprintf("%d:%d", raumfeld.MinBalance, raumfeld.MaxBalance),
 				Retained:   true,
 				Settable:   true,
-			}),
+			},
+			homie.Property{
+				NodeID:     nodeID,
+				PropertyID: "loudness",
+				Name:       "Loudness",
+				DataType:   homie.DataTypeBoolean,
+				Retained:   true,
+				Settable:   true,
+			},
 		)
-		if err != nil {
-			return err
-		}
 	}
 
-	return b.Broker.PublishDevice(device)
+	return append(properties,
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "transport",
+			Name:       "Transport",
+			DataType:   homie.DataTypeEnum,
+			Format:     strings.Join(raumfeld.TransportActions, ","),
+			Retained:   false,
+			Settable:   true,
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "seek",
+			Name:       "Seek",
+			DataType:   homie.DataTypeInteger,
+			Format:     "0:",
+			Retained:   false,
+			Settable:   true,
+			Unit:       "s",
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "transport-state",
+			Name:       "Transport State",
+			DataType:   homie.DataTypeEnum,
+			Format:     "STOPPED,PLAYING,PAUSED_PLAYBACK,TRANSITIONING,NO_MEDIA_PRESENT",
+			Retained:   true,
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "title",
+			Name:       "Title",
+			DataType:   homie.DataTypeString,
+			Retained:   true,
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "artist",
+			Name:       "Artist",
+			DataType:   homie.DataTypeString,
+			Retained:   true,
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "album",
+			Name:       "Album",
+			DataType:   homie.DataTypeString,
+			Retained:   true,
+		},
+		homie.Property{
+			NodeID:     nodeID,
+			PropertyID: "album-art-url",
+			Name:       "Album Art URL",
+			DataType:   homie.DataTypeString,
+			Retained:   true,
+		},
+	)
+}
+
+// volumeProperties maps the speaker channels to their volume properties.
+var volumeProperties = map[string]string{
+	raumfeld.ChannelMaster: "volume",
+	raumfeld.ChannelLF:     "volume-lf",
+	raumfeld.ChannelRF:     "volume-rf",
 }
 
 func (b *HomieBridge) OnVolumeChange(id string, volume int, channel string) {
-	logrus.Infof("volume changed on speaker %#v to %#v", id, volume)
-	b.Broker.PublishValue(id, "volume", float64(volume)/100.)
+	b.Speakers.Touch(id)
+
+	propertyID, found := volumeProperties[channel]
+	if !found {
+		logrus.Debugf("ignoring volume change of unknown channel %#v on speaker %#v", channel, id)
+		return
+	}
+
+	logrus.Infof("volume of channel %#v changed on speaker %#v to %#v", channel, id, volume)
+	b.Broker.PublishValue(id, propertyID, float64(volume)/100.)
 }
 
 func (b *HomieBridge) OnMuteChange(id string, muted bool, channel string) {
+	b.Speakers.Touch(id)
+	if channel != raumfeld.ChannelMaster {
+		logrus.Debugf("ignoring mute change of channel %#v on speaker %#v", channel, id)
+		return
+	}
+
 	logrus.Infof("mute changed on speaker %#v to %#v", id, muted)
 	b.Broker.PublishValue(id, "mute", muted)
 }
 
+func (b *HomieBridge) OnBassChange(id string, bass int) {
+	b.Speakers.Touch(id)
+	logrus.Infof("bass changed on speaker %#v to %#v", id, bass)
+	b.Broker.PublishValue(id, "bass", bass)
+}
+
+func (b *HomieBridge) OnTrebleChange(id string, treble int) {
+	b.Speakers.Touch(id)
+	logrus.Infof("treble changed on speaker %#v to %#v", id, treble)
+	b.Broker.PublishValue(id, "treble", treble)
+}
+
+func (b *HomieBridge) OnBalanceChange(id string, balance int) {
+	b.Speakers.Touch(id)
+	logrus.Infof("balance changed on speaker %#v to %#v", id, balance)
+	b.Broker.PublishValue(id, "balance", balance)
+}
+
+func (b *HomieBridge) OnLoudnessChange(id string, loudness bool, channel string) {
+	b.Speakers.Touch(id)
+	if channel != raumfeld.ChannelMaster {
+		logrus.Debugf("ignoring loudness change of channel %#v on speaker %#v", channel, id)
+		return
+	}
+
+	logrus.Infof("loudness changed on speaker %#v to %#v", id, loudness)
+	b.Broker.PublishValue(id, "loudness", loudness)
+}
+
 func (b *HomieBridge) OnPowerStateChange(id, state string) {
+	b.Speakers.Touch(id)
 	logrus.Infof("power state changed on speaker %#v to %#v", id, state)
-	b.Broker.PublishValue(id, "onoff", state != "MANUAL_STANDBY")
+	b.Broker.PublishValue(id, "onoff", state != raumfeld.PowerStateManualStandby)
+	b.Broker.PublishValue(id, "power-state", state)
+}
+
+func (b *HomieBridge) OnTransportStateChange(id, state string) {
+	b.Speakers.Touch(id)
+	logrus.Infof("transport state changed on speaker %#v to %#v", id, state)
+	b.Broker.PublishValue(id, "transport-state", state)
+}
+
+func (b *HomieBridge) OnTrackChange(id string, track raumfeld.Track) {
+	b.Speakers.Touch(id)
+	logrus.Infof("track changed on speaker %#v to %#v", id, track.Title)
+	b.Broker.PublishValue(id, "title", track.Title)
+	b.Broker.PublishValue(id, "artist", track.Artist)
+	b.Broker.PublishValue(id, "album", track.Album)
+	b.Broker.PublishValue(id, "album-art-url", track.AlbumArtURL)
+}
+
+// OnSubscriptionStatus records the problem per service, so a successful
+// subscription of one service does not hide a failed one of another.
+func (b *HomieBridge) OnSubscriptionStatus(id string, service string, err error) {
+	b.SetProblem(id, ProblemSubscription+"/"+service, err)
+}
+
+// OnTargetStatus gets called by the broker after a set command either got
+// confirmed by the speaker or failed.
+func (b *HomieBridge) OnTargetStatus(nodeID, propertyID string, err error) {
+	if err != nil {
+		logrus.WithError(err).Warn("command was not confirmed")
+	}
+	b.SetProblem(nodeID, ProblemTarget+"/"+propertyID, err)
 }
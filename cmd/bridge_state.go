@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/svenwltr/devilctl/pkg/dal/homie"
+)
+
+const (
+	ProblemConnect      = "connect"
+	ProblemSubscription = "subscription"
+	ProblemState        = "state"
+	ProblemTarget       = "target"
+)
+
+// SetProblem records or clears (with a nil error) a problem of a speaker. The
+// device goes into the alert state as long as any speaker has a problem.
+func (b *HomieBridge) SetProblem(speakerID, source string, err error) {
+	b.stateMu.Lock()
+	if b.problems == nil {
+		b.problems = map[string]map[string]error{}
+	}
+
+	if err != nil {
+		if b.problems[speakerID] == nil {
+			b.problems[speakerID] = map[string]error{}
+		}
+		b.problems[speakerID][source] = err
+	} else {
+		delete(b.problems[speakerID], source)
+		if len(b.problems[speakerID]) == 0 {
+			delete(b.problems, speakerID)
+		}
+	}
+	b.stateMu.Unlock()
+
+	b.publishDeviceState()
+}
+
+// HasProblem returns whether the speaker currently has a problem from the
+// given source.
+func (b *HomieBridge) HasProblem(speakerID, source string) bool {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	_, found := b.problems[speakerID][source]
+	return found
+}
+
+// ClearProblems forgets all problems of a speaker, which is useful after it
+// got removed.
+func (b *HomieBridge) ClearProblems(speakerID string) {
+	b.stateMu.Lock()
+	delete(b.problems, speakerID)
+	b.stateMu.Unlock()
+
+	b.publishDeviceState()
+}
+
+// beginInit puts the device into the init state while the definitions get
+// published. It returns a function that restores the actual state.
+func (b *HomieBridge) beginInit() func() {
+	b.stateMu.Lock()
+	b.initializing++
+	b.stateMu.Unlock()
+
+	b.publishDeviceState()
+
+	return func() {
+		b.stateMu.Lock()
+		b.initializing--
+		b.stateMu.Unlock()
+
+		b.publishDeviceState()
+	}
+}
+
+// publishDeviceState derives the device state from the problems and publishes
+// it. The state is computed and published while holding publishStateMu, so a
+// concurrent call cannot overwrite a newer state with an outdated one.
+func (b *HomieBridge) publishDeviceState() {
+	b.publishStateMu.Lock()
+	defer b.publishStateMu.Unlock()
+
+	b.stateMu.Lock()
+	state := homie.StateReady
+	switch {
+	case b.initializing > 0:
+		state = homie.StateInit
+	case len(b.problems) > 0:
+		state = homie.StateAlert
+	}
+	b.stateMu.Unlock()
+
+	err := b.Broker.SetState(state)
+	if err != nil {
+		logrus.WithError(err).Errorf("failed to publish device state %#v", state)
+	}
+}
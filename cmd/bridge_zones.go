@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/svenwltr/devilctl/pkg/dal/homie"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
+)
+
+const (
+	ZoneManagerNodeID = "zones"
+	ZoneNodePrefix    = "zone-"
+)
+
+// RefreshZones reads the current zone configuration from the Raumfeld host. It
+// discovers the host first, if it is not known yet. Without SSDP, zones are
+// only available if the host is given explicitly.
+func (b *HomieBridge) RefreshZones(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	host, _ := b.zoneConfig()
+	if host == nil && b.DisableSSDP {
+		b.noHostWarning.Do(func() {
+			logrus.Warn("zones are disabled, because SSDP discovery is disabled and no --raumfeld-host is given")
+		})
+		return nil
+	}
+
+	if host == nil {
+		var err error
+		host, err = raumfeld.DiscoverHost(ctx)
+		if err != nil {
+			return fmt.Errorf("discover raumfeld host: %w", err)
+		}
+	}
+
+	zones, err := host.Zones(ctx)
+	if err != nil {
+		return fmt.Errorf("get zones: %w", err)
+	}
+
+	logrus.Infof("found %d zones", len(zones.Zones))
+
+	b.zonesMu.Lock()
+	b.Host = host
+	b.Zones = zones
+	b.zonesMu.Unlock()
+
+	return nil
+}
+
+// zoneConfig returns the Raumfeld host and the last known zone
+// configuration. The host is nil, if it is not discovered yet.
+func (b *HomieBridge) zoneConfig() (*raumfeld.Host, raumfeld.ZoneConfig) {
+	b.zonesMu.RLock()
+	defer b.zonesMu.RUnlock()
+
+	return b.Host, b.Zones
+}
+
+func (b *HomieBridge) HandleZoneAction(nodeID, propertyID, value string) error {
+	host, zones := b.zoneConfig()
+	if host == nil {
+		return fmt.Errorf("raumfeld host not discovered yet")
+	}
+
+	ctx := context.Background()
+
+	// The host gets refreshed afterwards with its own timeout, therefore
+	// only the action itself uses this one.
+	actionCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var err error
+	switch {
+	case nodeID == ZoneManagerNodeID && propertyID == "group":
+		err = groupRooms(actionCtx, host, zones, value)
+
+	case strings.HasPrefix(nodeID, ZoneNodePrefix) && propertyID == "add-room":
+		err = addRoomToZone(actionCtx, host, zones, strings.TrimPrefix(nodeID, ZoneNodePrefix), value)
+
+	case strings.HasPrefix(nodeID, ZoneNodePrefix) && propertyID == "remove-room":
+		err = removeRoomFromZone(actionCtx, host, zones, value)
+
+	default:
+		return fmt.Errorf("no zone action for property %#v of node %#v", propertyID, nodeID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = b.RefreshZones(ctx)
+	if err != nil {
+		return err
+	}
+
+	return b.PublishHomieDefinitions(ctx)
+}
+
+func groupRooms(ctx context.Context, host *raumfeld.Host, zones raumfeld.ZoneConfig, value string) error {
+	udns := []string{}
+	for _, ref := range strings.Split(value, ",") {
+		room, err := findRoom(zones, ref)
+		if err != nil {
+			return err
+		}
+		udns = append(udns, room.UDN)
+	}
+
+	return host.ConnectRoomsToZone(ctx, "", udns...)
+}
+
+func addRoomToZone(ctx context.Context, host *raumfeld.Host, zones raumfeld.ZoneConfig, zoneID, value string) error {
+	room, err := findRoom(zones, value)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones.Zones {
+		if zone.ID == zoneID {
+			return host.ConnectRoomsToZone(ctx, zone.UDN, room.UDN)
+		}
+	}
+
+	return fmt.Errorf("zone %#v not found in cache", zoneID)
+}
+
+func removeRoomFromZone(ctx context.Context, host *raumfeld.Host, zones raumfeld.ZoneConfig, value string) error {
+	room, err := findRoom(zones, value)
+	if err != nil {
+		return err
+	}
+
+	return host.DropRoom(ctx, room.UDN)
+}
+
+func findRoom(zones raumfeld.ZoneConfig, ref string) (raumfeld.Room, error) {
+	ref = strings.TrimSpace(ref)
+	room, found := zones.FindRoom(ref)
+	if !found {
+		return raumfeld.Room{}, fmt.Errorf("room %#v not found in cache", ref)
+	}
+
+	return room, nil
+}
+
+// PublishZoneDefinitions publishes the zone manager node and one node per
+// zone. It returns the IDs of all published nodes.
+func (b *HomieBridge) PublishZoneDefinitions() ([]string, error) {
+	host, zones := b.zoneConfig()
+	if host == nil {
+		return nil, nil
+	}
+
+	nodeIDs := []string{ZoneManagerNodeID}
+	err := errors.Join(
+		b.Broker.PublishNode(homie.Node{
+			NodeID:      ZoneManagerNodeID,
+			Name:        "Zones",
+			Type:        "Zone Manager",
+			PropertyIDs: []string{"group"},
+		}),
+		b.Broker.PublishProperty(homie.Property{
+			NodeID:     ZoneManagerNodeID,
+			PropertyID: "group",
+			Name:       "Group Rooms",
+			DataType:   homie.DataTypeString,
+			Retained:   false,
+			Settable:   true,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, zone := range zones.Zones {
+		nodeID := ZoneNodePrefix + zone.ID
+		nodeIDs = append(nodeIDs, nodeID)
+
+		names := []string{}
+		for _, room := range zone.Rooms {
+			names = append(names, room.Name)
+		}
+
+		err := errors.Join(
+			b.Broker.PublishNode(homie.Node{
+				NodeID:      nodeID,
+				Name:        strings.Join(names, " + "),
+				Type:        "Zone",
+				PropertyIDs: []string{"rooms", "add-room", "remove-room"},
+			}),
+			b.Broker.PublishProperty(homie.Property{
+				NodeID:     nodeID,
+				PropertyID: "rooms",
+				Name:       "Rooms",
+				DataType:   homie.DataTypeString,
+				Retained:   true,
+			}),
+			b.Broker.PublishProperty(homie.Property{
+				NodeID:     nodeID,
+				PropertyID: "add-room",
+				Name:       "Add Room",
+				DataType:   homie.DataTypeString,
+				Retained:   false,
+				Settable:   true,
+			}),
+			b.Broker.PublishProperty(homie.Property{
+				NodeID:     nodeID,
+				PropertyID: "remove-room",
+				Name:       "Remove Room",
+				DataType:   homie.DataTypeString,
+				Retained:   false,
+				Settable:   true,
+			}),
+			b.Broker.PublishValue(nodeID, "rooms", strings.Join(names, ",")),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nodeIDs, nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
+)
+
+type ControlFunc func(ctx context.Context, speaker raumfeld.Speaker, args []string) error
+
+// ControlRunner resolves the speaker from the first positional argument and
+// passes the remaining arguments to the control function.
+type ControlRunner struct {
+	cmd       *cobra.Command
+	locations []string
+
+	Args    int
+	Control ControlFunc
+}
+
+func (r *ControlRunner) Bind(cmd *cobra.Command) error {
+	r.cmd = cmd
+	cmd.Args = cobra.ExactArgs(r.Args + 1)
+	cmd.PersistentFlags().StringSliceVar(
+		&r.locations, "location", nil,
+		`Location of the UPnP description of a speaker. Disables SSDP discovery, if set. `+
+			`Defaults to the comma separated list in $`+EnvLocations+`.`)
+	return nil
+}
+
+func (r *ControlRunner) Run(ctx context.Context) error {
+	args := r.cmd.Flags().Args()
+
+	locations, err := ParseLocations(locationsOrEnv(r.locations))
+	if err != nil {
+		return err
+	}
+
+	speaker, err := ResolveSpeaker(ctx, args[0], locations)
+	if err != nil {
+		return err
+	}
+
+	return r.Control(ctx, speaker, args[1:])
+}
+
+// ResolveSpeaker finds a speaker by its ID or its friendly name. It only
+// considers the given locations, if there are any. Otherwise it discovers the
+// speakers via SSDP.
+func ResolveSpeaker(ctx context.Context, ref string, locations []*url.URL) (raumfeld.Speaker, error) {
+	speakers := map[string]raumfeld.Speaker{}
+
+	if len(locations) == 0 {
+		discovered, err := raumfeld.Discover(ctx)
+		if err != nil {
+			return raumfeld.Speaker{}, fmt.Errorf("discover speakers: %w", err)
+		}
+		speakers = discovered
+	}
+
+	for _, location := range locations {
+		speaker, err := raumfeld.New(ctx, location)
+		if err != nil {
+			logrus.WithError(err).Warnf("failed to connect to %#v", location.String())
+			continue
+		}
+		speakers[speaker.ID()] = speaker
+	}
+
+	if speaker, found := speakers[ref]; found {
+		return speaker, nil
+	}
+
+	for _, speaker := range speakers {
+		if strings.EqualFold(speaker.FriendlyName(), ref) {
+			return speaker, nil
+		}
+	}
+
+	return raumfeld.Speaker{}, fmt.Errorf("speaker %#v not found", ref)
+}
+
+func ParseOnOff(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "on", "true", "1", "yes":
+		return true, nil
+	case "off", "false", "0", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid value %#v, expected on or off", value)
+	}
+}
+
+func ControlVolume(ctx context.Context, speaker raumfeld.Speaker, args []string) error {
+	volume, err := strconv.ParseUint(args[0], 10, 16)
+	if err != nil || volume > 100 {
+		return fmt.Errorf("invalid volume %#v, expected a value between 0 and 100", args[0])
+	}
+
+	return speaker.SetVolumePercent(ctx, uint16(volume))
+}
+
+func ControlMute(ctx context.Context, speaker raumfeld.Speaker, args []string) error {
+	muted, err := ParseOnOff(args[0])
+	if err != nil {
+		return err
+	}
+
+	return speaker.SetMute(ctx, muted)
+}
+
+func ControlPower(ctx context.Context, speaker raumfeld.Speaker, args []string) error {
+	on, err := ParseOnOff(args[0])
+	if err != nil {
+		return err
+	}
+
+	return speaker.SetOnOff(ctx, on)
+}
+
+// ControlTransport returns a control function, that runs the given transport
+// action on the speaker.
+func ControlTransport(action string) ControlFunc {
+	return func(ctx context.Context, speaker raumfeld.Speaker, args []string) error {
+		return speaker.SetTransport(ctx, action)
+	}
+}
@@ -2,64 +2,202 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
-	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
 )
 
-func DiscoverRunner(ctx context.Context) error {
+const (
+	OutputText  = "text"
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+type DiscoverRunner struct {
+	output  string
+	timeout time.Duration
+}
+
+func (r *DiscoverRunner) Bind(cmd *cobra.Command) error {
+	cmd.PersistentFlags().StringVarP(
+		&r.output, "output", "o", OutputText,
+		`Output format. One of "text", "table", "json" or "yaml".`)
+	cmd.PersistentFlags().DurationVar(
+		&r.timeout, "timeout", 10*time.Second,
+		`Maximum time to wait for the state of a single speaker.`)
+	return nil
+}
+
+type DiscoveredSpeaker struct {
+	ID             string `json:"id" yaml:"id"`
+	Name           string `json:"name" yaml:"name"`
+	Location       string `json:"location" yaml:"location"`
+	MDNSLocation   string `json:"mdnsLocation" yaml:"mdnsLocation"`
+	LocalAddr      string `json:"localAddr" yaml:"localAddr"`
+	PowerState     string `json:"powerState,omitempty" yaml:"powerState,omitempty"`
+	TransportState string `json:"transportState,omitempty" yaml:"transportState,omitempty"`
+	Volume         *int   `json:"volume,omitempty" yaml:"volume,omitempty"`
+	Muted          *bool  `json:"muted,omitempty" yaml:"muted,omitempty"`
+	Error          string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+func (r *DiscoverRunner) Run(ctx context.Context) error {
+	write, err := discoverWriter(r.output)
+	if err != nil {
+		return err
+	}
+
 	speakers, err := raumfeld.Discover(ctx)
 	if err != nil {
 		return err
 	}
 
-	eg, ctx := errgroup.WithContext(ctx)
-
-	for usn, speaker := range speakers {
-		fmt.Printf("---------\n")
-		fmt.Printf("Name:             %v\n", speaker.FriendlyName())
-		fmt.Printf("ID:               %v\n", usn)
-		fmt.Printf("Location:         %v\n", speaker.TryMDNSLocation().String())
-		fmt.Printf("Discovered From:  %v\n", speaker.LocalAddr())
-
-		var (
-			waitVolume     = make(chan struct{})
-			waitPowerState = make(chan struct{})
-			waitMuted      = make(chan struct{})
-		)
-
-		srv, err := raumfeld.NewSubsciptionServer(
-			raumfeld.SubscribeHandlerFuncs{
-				VolumeChange: func(id string, volume int, channel string) {
-					fmt.Printf("Volume:           %v\n", volume)
-					close(waitVolume)
-				},
-				MuteChange: func(id string, muted bool, channel string) {
-					fmt.Printf("Muted:            %v\n", muted)
-					close(waitMuted)
-				},
-				PowerStateChange: func(id, state string) {
-					fmt.Printf("PowerState:       %v\n", state)
-					close(waitPowerState)
-				},
-			},
-		)
-		if err != nil {
-			return err
+	list := make([]raumfeld.Speaker, 0, len(speakers))
+	for _, speaker := range speakers {
+		list = append(list, speaker)
+	}
+
+	result := probeSpeakers(list, func(speaker raumfeld.Speaker) DiscoveredSpeaker {
+		return probeSpeaker(ctx, speaker, r.timeout)
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return write(os.Stdout, result)
+}
+
+// probeSpeakers runs the probe for all speakers in parallel, so a single
+// unresponsive speaker only delays the result by its timeout. The results
+// have the same order as the speakers.
+func probeSpeakers[T any](speakers []raumfeld.Speaker, probe func(raumfeld.Speaker) T) []T {
+	result := make([]T, len(speakers))
+
+	var wg sync.WaitGroup
+	for i, speaker := range speakers {
+		wg.Add(1)
+		go func(i int, speaker raumfeld.Speaker) {
+			defer wg.Done()
+			result[i] = probe(speaker)
+		}(i, speaker)
+	}
+	wg.Wait()
+
+	return result
+}
+
+func probeSpeaker(ctx context.Context, speaker raumfeld.Speaker, timeout time.Duration) DiscoveredSpeaker {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := DiscoveredSpeaker{
+		ID:           speaker.ID(),
+		Name:         speaker.FriendlyName(),
+		Location:     speaker.Location().String(),
+		MDNSLocation: speaker.TryMDNSLocation(ctx).String(),
+		LocalAddr:    speaker.LocalAddr().String(),
+	}
+
+	state, err := speaker.State(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.PowerState = state.PowerState
+	result.TransportState = state.TransportState
+	result.Volume = &state.Volume
+	result.Muted = &state.Muted
+
+	return result
+}
+
+type discoverWriteFunc func(io.Writer, []DiscoveredSpeaker) error
+
+func discoverWriter(output string) (discoverWriteFunc, error) {
+	switch output {
+	case OutputText:
+		return writeDiscoverText, nil
+	case OutputTable:
+		return writeDiscoverTable, nil
+	case OutputJSON:
+		return writeDiscoverJSON, nil
+	case OutputYAML:
+		return writeDiscoverYAML, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %#v", output)
+	}
+}
+
+func writeDiscoverText(w io.Writer, speakers []DiscoveredSpeaker) error {
+	for _, speaker := range speakers {
+		fmt.Fprintf(w, "---------\n")
+		fmt.Fprintf(w, "Name:             %v\n", speaker.Name)
+		fmt.Fprintf(w, "ID:               %v\n", speaker.ID)
+		fmt.Fprintf(w, "Location:         %v\n", speaker.MDNSLocation)
+		fmt.Fprintf(w, "Discovered From:  %v\n", speaker.LocalAddr)
+
+		if speaker.Error != "" {
+			fmt.Fprintf(w, "Error:            %v\n", speaker.Error)
+			continue
 		}
 
-		go srv.Run(ctx)
+		fmt.Fprintf(w, "PowerState:       %v\n", speaker.PowerState)
+		fmt.Fprintf(w, "TransportState:   %v\n", speaker.TransportState)
+		fmt.Fprintf(w, "Volume:           %v\n", *speaker.Volume)
+		fmt.Fprintf(w, "Muted:            %v\n", *speaker.Muted)
+	}
+
+	return nil
+}
 
-		err = srv.Subscribe(speaker)
-		if err != nil {
-			return err
+func writeDiscoverTable(w io.Writer, speakers []DiscoveredSpeaker) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tLOCATION\tMDNS LOCATION\tLOCAL ADDR\tPOWER STATE\tVOLUME\tMUTED\tERROR")
+
+	for _, speaker := range speakers {
+		volume, muted := "", ""
+		if speaker.Volume != nil {
+			volume = fmt.Sprint(*speaker.Volume)
+		}
+		if speaker.Muted != nil {
+			muted = fmt.Sprint(*speaker.Muted)
 		}
 
-		<-waitVolume
-		<-waitPowerState
-		<-waitMuted
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			speaker.ID, speaker.Name, speaker.Location, speaker.MDNSLocation,
+			speaker.LocalAddr, speaker.PowerState, volume, muted, speaker.Error)
 	}
 
-	return eg.Wait()
+	return tw.Flush()
+}
+
+func writeDiscoverJSON(w io.Writer, speakers []DiscoveredSpeaker) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(speakers)
+}
+
+func writeDiscoverYAML(w io.Writer, speakers []DiscoveredSpeaker) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	err := enc.Encode(speakers)
+	if err != nil {
+		return err
+	}
+	return enc.Close()
 }
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// EnvLocations is the environment variable that contains a comma separated
+// list of speaker locations.
+const EnvLocations = "DEVILCTL_LOCATIONS"
+
+func LocationsFromEnv() []string {
+	result := []string{}
+	for _, location := range strings.Split(os.Getenv(EnvLocations), ",") {
+		location = strings.TrimSpace(location)
+		if location != "" {
+			result = append(result, location)
+		}
+	}
+	return result
+}
+
+// locationsOrEnv returns the given locations or the ones from the
+// environment, if there are none. It is not done via flag defaults, because
+// those would be shown in the help output.
+func locationsOrEnv(locations []string) []string {
+	if len(locations) > 0 {
+		return locations
+	}
+	return LocationsFromEnv()
+}
+
+func ParseLocations(locations []string) ([]*url.URL, error) {
+	result := []*url.URL{}
+	for _, location := range locations {
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("parse location %#v: %w", location, err)
+		}
+
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("location %#v must be an absolute URL", location)
+		}
+
+		result = append(result, u)
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/svenwltr/devilctl/pkg/dal/mqttconn"
+)
+
+// EnvMQTTPassword is the environment variable that contains the MQTT
+// password, so it does not show up in the process list.
+const EnvMQTTPassword = "DEVILCTL_MQTT_PASSWORD"
+
+func bindMQTTFlags(cmd *cobra.Command, opts *mqttconn.Options) {
+	cmd.PersistentFlags().StringVar(
+		&opts.Server, "broker", "",
+		`The broker MQTT URI. ex: tcp://10.10.1.1:1883`)
+	cmd.PersistentFlags().StringVar(
+		&opts.Username, "mqtt-username", "",
+		`Username for the MQTT broker.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.Password, "mqtt-password", "",
+		`Password for the MQTT broker. Defaults to $`+EnvMQTTPassword+`.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.PasswordFile, "mqtt-password-file", "",
+		`File that contains the password for the MQTT broker. Overrides --mqtt-password.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.CAFile, "mqtt-ca", "",
+		`PEM encoded CA bundle to verify the MQTT broker certificate.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.CertFile, "mqtt-cert", "",
+		`PEM encoded client certificate for the MQTT broker.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.KeyFile, "mqtt-key", "",
+		`PEM encoded private key of the client certificate.`)
+	cmd.PersistentFlags().BoolVar(
+		&opts.InsecureSkipVerify, "mqtt-insecure-skip-verify", false,
+		`Do not verify the certificate of the MQTT broker.`)
+	cmd.PersistentFlags().StringVar(
+		&opts.ClientID, "mqtt-client-id", "",
+		`Client ID for the MQTT connection. Required, if --mqtt-clean-session=false.`)
+	cmd.PersistentFlags().DurationVar(
+		&opts.KeepAlive, "mqtt-keepalive", 30*time.Second,
+		`Keepalive interval of the MQTT connection.`)
+	cmd.PersistentFlags().BoolVar(
+		&opts.CleanSession, "mqtt-clean-session", true,
+		`Start with a clean MQTT session on every connect.`)
+}
+
+// mqttOptionsFromEnv fills options that might be given by environment
+// variables. It is not done via flag defaults, because those would be shown
+// in the help output.
+func mqttOptionsFromEnv(opts mqttconn.Options) mqttconn.Options {
+	if opts.Password == "" {
+		opts.Password = os.Getenv(EnvMQTTPassword)
+	}
+	return opts
+}
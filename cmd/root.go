@@ -5,6 +5,7 @@ import (
 
 	"github.com/rebuy-de/rebuy-go-sdk/v5/pkg/cmdutil"
 	"github.com/spf13/cobra"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
 )
 
 const DeviceType = `urn:schemas-upnp-org:device:MediaRenderer:1`
@@ -27,12 +28,52 @@ func NewRootCommand() *cobra.Command {
 
 		cmdutil.WithSubCommand(cmdutil.New(
 			"discover", "discover hosts in network",
-			cmdutil.WithRunner(RunnerFunc(DiscoverRunner)),
+			cmdutil.WithRunner(new(DiscoverRunner)),
 		)),
 
 		cmdutil.WithSubCommand(cmdutil.New(
 			"homie-bridge", "Bridge Raumfeld speakers to MQTT via Homie convention",
 			cmdutil.WithRunner(new(HomieBridgeRunner)),
 		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"volume SPEAKER PERCENT", "set the volume of a speaker",
+			cmdutil.WithRunner(&ControlRunner{Args: 1, Control: ControlVolume}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"mute SPEAKER on|off", "mute or unmute a speaker",
+			cmdutil.WithRunner(&ControlRunner{Args: 1, Control: ControlMute}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"power SPEAKER on|off", "turn a speaker on or put it into standby",
+			cmdutil.WithRunner(&ControlRunner{Args: 1, Control: ControlPower}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"play SPEAKER", "start playback on a speaker",
+			cmdutil.WithRunner(&ControlRunner{Control: ControlTransport(raumfeld.TransportPlay)}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"pause SPEAKER", "pause playback on a speaker",
+			cmdutil.WithRunner(&ControlRunner{Control: ControlTransport(raumfeld.TransportPause)}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"stop SPEAKER", "stop playback on a speaker",
+			cmdutil.WithRunner(&ControlRunner{Control: ControlTransport(raumfeld.TransportStop)}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"next SPEAKER", "skip to the next track on a speaker",
+			cmdutil.WithRunner(&ControlRunner{Control: ControlTransport(raumfeld.TransportNext)}),
+		)),
+
+		cmdutil.WithSubCommand(cmdutil.New(
+			"previous SPEAKER", "skip to the previous track on a speaker",
+			cmdutil.WithRunner(&ControlRunner{Control: ControlTransport(raumfeld.TransportPrevious)}),
+		)),
 	)
 }
@@ -0,0 +1,153 @@
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
+)
+
+type Entry struct {
+	Speaker  raumfeld.Speaker
+	LastSeen time.Time
+}
+
+// Registry holds the currently known speakers and is safe for concurrent
+// use. All modifying functions return the resulting events, so the caller can
+// react on changes.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+func New() *Registry {
+	return &Registry{
+		entries: map[string]Entry{},
+	}
+}
+
+func (r *Registry) Get(id string) (raumfeld.Speaker, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, found := r.entries[id]
+	return entry.Speaker, found
+}
+
+func (r *Registry) Entry(id string) (Entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, found := r.entries[id]
+	return entry, found
+}
+
+// List returns all speakers ordered by ID.
+func (r *Registry) List() []raumfeld.Speaker {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]raumfeld.Speaker, 0, len(r.entries))
+	for _, entry := range r.entries {
+		result = append(result, entry.Speaker)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID() < result[j].ID()
+	})
+
+	return result
+}
+
+// Put adds or updates the speaker. A speaker counts as updated, if its
+// location changed.
+func (r *Registry) Put(speaker raumfeld.Speaker) []raumfeld.SpeakerEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.put(speaker)
+}
+
+func (r *Registry) put(speaker raumfeld.Speaker) []raumfeld.SpeakerEvent {
+	existing, found := r.entries[speaker.ID()]
+	r.entries[speaker.ID()] = Entry{
+		Speaker:  speaker,
+		LastSeen: time.Now(),
+	}
+
+	switch {
+	case !found:
+		return []raumfeld.SpeakerEvent{{
+			Type:    raumfeld.SpeakerAdded,
+			ID:      speaker.ID(),
+			Speaker: speaker,
+		}}
+
+	case existing.Speaker.Location().String() != speaker.Location().String():
+		return []raumfeld.SpeakerEvent{{
+			Type:    raumfeld.SpeakerUpdated,
+			ID:      speaker.ID(),
+			Speaker: speaker,
+		}}
+
+	default:
+		return nil
+	}
+}
+
+func (r *Registry) Remove(id string) []raumfeld.SpeakerEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.remove(id)
+}
+
+func (r *Registry) remove(id string) []raumfeld.SpeakerEvent {
+	existing, found := r.entries[id]
+	if !found {
+		return nil
+	}
+
+	delete(r.entries, id)
+
+	return []raumfeld.SpeakerEvent{{
+		Type:    raumfeld.SpeakerRemoved,
+		ID:      id,
+		Speaker: existing.Speaker,
+	}}
+}
+
+// Replace sets the given speakers and removes all others.
+func (r *Registry) Replace(speakers map[string]raumfeld.Speaker) []raumfeld.SpeakerEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := []raumfeld.SpeakerEvent{}
+
+	for id := range r.entries {
+		if _, found := speakers[id]; !found {
+			events = append(events, r.remove(id)...)
+		}
+	}
+
+	for _, speaker := range speakers {
+		events = append(events, r.put(speaker)...)
+	}
+
+	return events
+}
+
+// Touch updates the last seen timestamp of the speaker, if it is known.
+func (r *Registry) Touch(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, found := r.entries[id]
+	if !found {
+		return
+	}
+
+	entry.LastSeen = time.Now()
+	r.entries[id] = entry
+}
@@ -0,0 +1,173 @@
+package registry
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld/raumfeldtest"
+)
+
+// speakerFactory creates speakers from descriptions of a test server.
+type speakerFactory struct {
+	t      *testing.T
+	server *raumfeldtest.Server
+}
+
+func newSpeakerFactory(t *testing.T) *speakerFactory {
+	return &speakerFactory{t: t, server: raumfeldtest.NewServer(t)}
+}
+
+func (f *speakerFactory) speaker(id, file string) raumfeld.Speaker {
+	location, err := url.Parse(f.server.Location(id, file))
+	require.NoError(f.t, err)
+
+	speaker, err := raumfeld.New(context.Background(), location)
+	require.NoError(f.t, err)
+	require.Equal(f.t, id, speaker.ID())
+
+	return speaker
+}
+
+type event struct {
+	Type raumfeld.SpeakerEventType
+	ID   string
+}
+
+func simplify(events []raumfeld.SpeakerEvent) []event {
+	result := []event{}
+	for _, e := range events {
+		result = append(result, event{Type: e.Type, ID: e.ID})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+func TestRegistryPutRemove(t *testing.T) {
+	f := newSpeakerFactory(t)
+	r := New()
+
+	for _, tc := range []struct {
+		name   string
+		action func() []raumfeld.SpeakerEvent
+		want   []event
+	}{
+		{
+			name:   "add new speaker",
+			action: func() []raumfeld.SpeakerEvent { return r.Put(f.speaker("kitchen", "a.xml")) },
+			want:   []event{{raumfeld.SpeakerAdded, "kitchen"}},
+		},
+		{
+			name:   "put same location",
+			action: func() []raumfeld.SpeakerEvent { return r.Put(f.speaker("kitchen", "a.xml")) },
+			want:   []event{},
+		},
+		{
+			name:   "put changed location",
+			action: func() []raumfeld.SpeakerEvent { return r.Put(f.speaker("kitchen", "b.xml")) },
+			want:   []event{{raumfeld.SpeakerUpdated, "kitchen"}},
+		},
+		{
+			name:   "remove unknown speaker",
+			action: func() []raumfeld.SpeakerEvent { return r.Remove("bath") },
+			want:   []event{},
+		},
+		{
+			name:   "remove known speaker",
+			action: func() []raumfeld.SpeakerEvent { return r.Remove("kitchen") },
+			want:   []event{{raumfeld.SpeakerRemoved, "kitchen"}},
+		},
+		{
+			name:   "remove again",
+			action: func() []raumfeld.SpeakerEvent { return r.Remove("kitchen") },
+			want:   []event{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, simplify(tc.action()))
+		})
+	}
+
+	require.Empty(t, r.List())
+}
+
+func TestRegistryReplace(t *testing.T) {
+	f := newSpeakerFactory(t)
+	r := New()
+
+	for _, tc := range []struct {
+		name     string
+		speakers []raumfeld.Speaker
+		want     []event
+		wantIDs  []string
+	}{
+		{
+			name:     "initial",
+			speakers: []raumfeld.Speaker{f.speaker("kitchen", "a.xml"), f.speaker("bath", "a.xml")},
+			want:     []event{{raumfeld.SpeakerAdded, "bath"}, {raumfeld.SpeakerAdded, "kitchen"}},
+			wantIDs:  []string{"bath", "kitchen"},
+		},
+		{
+			name:     "unchanged",
+			speakers: []raumfeld.Speaker{f.speaker("kitchen", "a.xml"), f.speaker("bath", "a.xml")},
+			want:     []event{},
+			wantIDs:  []string{"bath", "kitchen"},
+		},
+		{
+			name:     "add, update and remove",
+			speakers: []raumfeld.Speaker{f.speaker("kitchen", "b.xml"), f.speaker("office", "a.xml")},
+			want: []event{
+				{raumfeld.SpeakerRemoved, "bath"},
+				{raumfeld.SpeakerUpdated, "kitchen"},
+				{raumfeld.SpeakerAdded, "office"},
+			},
+			wantIDs: []string{"kitchen", "office"},
+		},
+		{
+			name:     "empty",
+			speakers: nil,
+			want:     []event{{raumfeld.SpeakerRemoved, "kitchen"}, {raumfeld.SpeakerRemoved, "office"}},
+			wantIDs:  []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			speakers := map[string]raumfeld.Speaker{}
+			for _, speaker := range tc.speakers {
+				speakers[speaker.ID()] = speaker
+			}
+
+			require.Equal(t, tc.want, simplify(r.Replace(speakers)))
+
+			ids := []string{}
+			for _, speaker := range r.List() {
+				ids = append(ids, speaker.ID())
+			}
+			require.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
+
+func TestRegistryTouch(t *testing.T) {
+	f := newSpeakerFactory(t)
+	r := New()
+
+	r.Touch("kitchen")
+	_, found := r.Entry("kitchen")
+	require.False(t, found)
+
+	r.Put(f.speaker("kitchen", "a.xml"))
+	before, found := r.Entry("kitchen")
+	require.True(t, found)
+
+	r.Touch("kitchen")
+	after, found := r.Entry("kitchen")
+	require.True(t, found)
+	require.False(t, after.LastSeen.Before(before.LastSeen))
+}
@@ -0,0 +1,427 @@
+package homeassistant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+	"github.com/svenwltr/devilctl/pkg/dal/homie"
+	"github.com/svenwltr/devilctl/pkg/dal/mqttconn"
+)
+
+const (
+	DiscoveryPrefix = "homeassistant"
+
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+)
+
+// Broker publishes Home Assistant MQTT discovery configs. It accepts the same
+// device, node and property definitions as the Homie broker, so both can be
+// used by the same bridge. Every node becomes a device in Home Assistant and
+// every property an entity of that device.
+type Broker struct {
+	client    mqtt.Client
+	baseTopic string
+	actions   *mqttconn.Queue
+
+	mu            sync.RWMutex
+	nodes         map[string]homie.Node
+	actionHandler func(string, string, string) error
+	connected     bool
+	status        string
+
+	// configs contains the published config topics by node and property ID,
+	// so the entities can be removed together with their node or property.
+	configs map[string]map[string]string
+}
+
+// New creates a broker that publishes the states and commands of the nodes
+// below "<deviceID>/". The device ID also distinguishes the entities of
+// multiple bridges on the same broker.
+func New(conn mqttconn.Options, deviceID string) (*Broker, error) {
+	err := homie.ValidateID(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	baseTopic := deviceID
+
+	opts, err := conn.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.SetWill(path.Join(baseTopic, "status"), PayloadOffline, homie.QOSAtLeastOnce, true)
+
+	broker := &Broker{
+		baseTopic: baseTopic,
+		nodes:     map[string]homie.Node{},
+		configs:   map[string]map[string]string{},
+	}
+
+	broker.actions = mqttconn.NewQueue(broker.handleAction)
+	opts.SetOnConnectHandler(broker.onConnect)
+
+	client, err := mqttconn.Connect(opts)
+	if err != nil {
+		broker.actions.Close()
+		return nil, err
+	}
+	broker.client = client
+
+	err = broker.subscribe(client)
+	if err != nil {
+		client.Disconnect(1000)
+		broker.actions.Close()
+		return nil, err
+	}
+
+	return broker, nil
+}
+
+// onConnect restores the subscription and the availability after a
+// reconnect, since the subscription is lost with a clean session and the
+// broker published the will. The initial subscription is done by New.
+func (b *Broker) onConnect(client mqtt.Client) {
+	b.mu.Lock()
+	reconnect := b.connected
+	b.connected = true
+	status := b.status
+	b.mu.Unlock()
+
+	if !reconnect {
+		return
+	}
+
+	err := b.subscribe(client)
+	if err != nil {
+		logrus.WithError(err).Error("failed to restore subscription after reconnect")
+	}
+
+	if status == "" {
+		return
+	}
+
+	token := client.Publish(path.Join(b.baseTopic, "status"), homie.QOSAtLeastOnce, true, status)
+	token.Wait()
+	if token.Error() != nil {
+		logrus.WithError(token.Error()).Error("failed to restore availability after reconnect")
+	}
+}
+
+func (b *Broker) subscribe(client mqtt.Client) error {
+	topic := path.Join(b.baseTopic, "+", "+", "set")
+
+	token := client.Subscribe(topic, homie.QOSAtMostOnce, b.actions.Handle)
+	token.Wait()
+	if token.Error() != nil {
+		return fmt.Errorf("subscribe %q: %w", topic, token.Error())
+	}
+
+	return nil
+}
+
+func (b *Broker) SetActionHandler(handler func(string, string, string) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.actionHandler = handler
+}
+
+// SetTargetHandler is a no-op, since Home Assistant has no concept of target
+// values.
+func (b *Broker) SetTargetHandler(handler func(string, string, error)) {}
+
+func (b *Broker) handleAction(client mqtt.Client, message mqtt.Message) {
+	b.mu.RLock()
+	actionHandler := b.actionHandler
+	b.mu.RUnlock()
+
+	if actionHandler == nil {
+		message.Ack()
+		return
+	}
+
+	topic := message.Topic()
+	topic = strings.TrimPrefix(topic, b.baseTopic)
+	topic = strings.TrimSuffix(topic, "set")
+	topic = strings.Trim(topic, "/")
+
+	nodeID, propertyID, ok := strings.Cut(topic, "/")
+	if !ok {
+		logrus.Errorf("invalid topic %#v", message.Topic())
+		return
+	}
+
+	err := actionHandler(nodeID, propertyID, string(message.Payload()))
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	message.Ack()
+}
+
+func (b *Broker) MustClose() {
+	err := b.Close()
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+func (b *Broker) Close() error {
+	err := b.SetState(homie.StateDisconnected)
+	if err != nil {
+		return err
+	}
+
+	b.client.Disconnect(1000)
+	b.actions.Close()
+	return nil
+}
+
+// PublishDevice removes the entities of all nodes, that are not part of the
+// device anymore. Otherwise Home Assistant has no concept of the bridge
+// device itself, since the nodes are already published as devices.
+func (b *Broker) PublishDevice(device homie.Device) error {
+	nodeIDs := map[string]bool{}
+	for _, nodeID := range device.NodeIDs {
+		nodeIDs[nodeID] = true
+	}
+
+	b.mu.Lock()
+	removed := map[string]map[string]string{}
+	for nodeID, configs := range b.configs {
+		if !nodeIDs[nodeID] {
+			removed[nodeID] = configs
+			delete(b.configs, nodeID)
+		}
+	}
+	for nodeID := range b.nodes {
+		if !nodeIDs[nodeID] {
+			delete(b.nodes, nodeID)
+		}
+	}
+	b.mu.Unlock()
+
+	errs := []error{}
+	for nodeID, configs := range removed {
+		errs = append(errs, b.clearEntities(nodeID, configs))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetState translates the Homie device state into the availability of the
+// entities. An alert still means that the bridge is working in general and
+// the init state is ignored to avoid flapping entities.
+func (b *Broker) SetState(state string) error {
+	var payload string
+	switch state {
+	case homie.StateInit:
+		return nil
+	case homie.StateReady, homie.StateAlert:
+		payload = PayloadOnline
+	default:
+		payload = PayloadOffline
+	}
+
+	b.mu.Lock()
+	b.status = payload
+	b.mu.Unlock()
+
+	return b.publish(path.Join(b.baseTopic, "status"), payload)
+}
+
+// PublishNode remembers the node, since Home Assistant expects the device
+// information within each entity config. It removes the entities of all
+// properties, that are not part of the node anymore.
+func (b *Broker) PublishNode(node homie.Node) error {
+	propertyIDs := map[string]bool{}
+	for _, propertyID := range node.PropertyIDs {
+		propertyIDs[propertyID] = true
+	}
+
+	b.mu.Lock()
+	b.nodes[node.NodeID] = node
+	removed := map[string]string{}
+	for propertyID, topic := range b.configs[node.NodeID] {
+		if !propertyIDs[propertyID] {
+			removed[propertyID] = topic
+			delete(b.configs[node.NodeID], propertyID)
+		}
+	}
+	b.mu.Unlock()
+
+	return b.clearEntities(node.NodeID, removed)
+}
+
+// clearEntities removes the retained configs and states of the given
+// properties, which are mapped to their config topics.
+func (b *Broker) clearEntities(nodeID string, configs map[string]string) error {
+	errs := []error{}
+	for propertyID, topic := range configs {
+		errs = append(errs,
+			b.publish(topic, ""),
+			b.publish(path.Join(b.baseTopic, nodeID, propertyID), ""),
+		)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (b *Broker) PublishProperty(property homie.Property) error {
+	err := property.Validate()
+	if err != nil {
+		return err
+	}
+
+	b.mu.RLock()
+	node, found := b.nodes[property.NodeID]
+	b.mu.RUnlock()
+	if !found {
+		return fmt.Errorf("node %#v of property %#v is not published", property.NodeID, property.PropertyID)
+	}
+
+	component, config, err := b.entityConfig(node, property)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("encode config of %#v: %w", property.PropertyID, err)
+	}
+
+	topic := b.configTopic(component, property)
+	err = b.publish(topic, string(payload))
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if b.configs[property.NodeID] == nil {
+		b.configs[property.NodeID] = map[string]string{}
+	}
+	b.configs[property.NodeID][property.PropertyID] = topic
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *Broker) PublishValue(nodeID, propertyID string, value any) error {
+	return b.publish(path.Join(b.baseTopic, nodeID, propertyID), homie.FormatValue(value))
+}
+
+func (b *Broker) configTopic(component string, property homie.Property) string {
+	objectID := fmt.Sprintf("%s_%s", property.NodeID, property.PropertyID)
+	return path.Join(DiscoveryPrefix, component, b.baseTopic, objectID, "config")
+}
+
+// unboundedNumber is used as limit for number entities without a minimum or
+// maximum.
+const unboundedNumber = float64(math.MaxInt32)
+
+func (b *Broker) entityConfig(node homie.Node, property homie.Property) (string, map[string]any, error) {
+	stateTopic := path.Join(b.baseTopic, property.NodeID, property.PropertyID)
+
+	config := map[string]any{
+		"name":               property.Name,
+		"unique_id":          fmt.Sprintf("%s_%s_%s", b.baseTopic, property.NodeID, property.PropertyID),
+		"availability_topic": path.Join(b.baseTopic, "status"),
+		"device": map[string]any{
+			"identifiers":  []string{property.NodeID},
+			"name":         node.Name,
+			"model":        node.Type,
+			"manufacturer": "Teufel Raumfeld",
+		},
+	}
+
+	if property.Retained {
+		config["state_topic"] = stateTopic
+	}
+	if property.Settable {
+		config["command_topic"] = path.Join(stateTopic, "set")
+	}
+	if property.Unit != "" {
+		config["unit_of_measurement"] = property.Unit
+	}
+
+	switch {
+	case property.DataType == homie.DataTypeBoolean && property.Settable:
+		config["payload_on"] = "true"
+		config["payload_off"] = "false"
+		config["state_on"] = "true"
+		config["state_off"] = "false"
+		return "switch", config, nil
+
+	case property.DataType == homie.DataTypeBoolean:
+		config["payload_on"] = "true"
+		config["payload_off"] = "false"
+		return "binary_sensor", config, nil
+
+	case (property.DataType == homie.DataTypeFloat || property.DataType == homie.DataTypeInteger) && property.Settable:
+		// Home Assistant defaults to a range of 1 to 100, therefore open ends
+		// need explicit limits.
+		min, max := -unboundedNumber, unboundedNumber
+		if property.Format != "" {
+			rangeMin, rangeMax, err := homie.ParseRange(property.DataType, property.Format)
+			if err != nil {
+				return "", nil, err
+			}
+			if rangeMin != nil {
+				min = *rangeMin
+			}
+			if rangeMax != nil {
+				max = *rangeMax
+			}
+		}
+		config["min"] = min
+		config["max"] = max
+		if min == -unboundedNumber || max == unboundedNumber {
+			// A slider is not usable without a range.
+			config["mode"] = "box"
+		}
+		if property.DataType == homie.DataTypeFloat {
+			config["step"] = 0.01
+		}
+		return "number", config, nil
+
+	case property.DataType == homie.DataTypeEnum && property.Settable:
+		config["options"] = strings.Split(property.Format, ",")
+		return "select", config, nil
+
+	case property.Settable:
+		return "text", config, nil
+
+	case property.DataType == homie.DataTypeEnum && property.Retained:
+		config["device_class"] = "enum"
+		config["options"] = strings.Split(property.Format, ",")
+		return "sensor", config, nil
+
+	case property.DataType == homie.DataTypeDateTime && property.Retained:
+		config["device_class"] = "timestamp"
+		return "sensor", config, nil
+
+	case property.Retained:
+		return "sensor", config, nil
+
+	default:
+		return "", nil, fmt.Errorf("property %#v is neither settable nor retained", property.PropertyID)
+	}
+}
+
+func (b *Broker) publish(topic string, message string) error {
+	token := b.client.Publish(topic, homie.QOSAtLeastOnce, true, message)
+	token.Wait()
+	if token.Error() != nil {
+		return fmt.Errorf("publish %q: %w", topic, token.Error())
+	}
+
+	return nil
+}
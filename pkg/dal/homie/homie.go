@@ -4,10 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	"github.com/sirupsen/logrus"
+	"github.com/svenwltr/devilctl/pkg/dal/mqttconn"
 )
 
 const (
@@ -19,38 +24,221 @@ const (
 type Broker struct {
 	client    mqtt.Client
 	baseTopic string
+	actions   *mqttconn.Queue
 
-	ActionHandler func(string, string, string) error
+	mu            sync.RWMutex
+	actionHandler func(string, string, string) error
+	targetHandler func(string, string, error)
+	state         string
+	connected     bool
+
+	// published contains all retained messages by topic, so they can be
+	// restored after a reconnect with a clean session.
+	published map[string]string
+
+	// homie5 enables the Homie 5 convention, which needs to collect all
+	// nodes and properties for the $description document.
+	homie5     bool
+	nodes      map[string]Node
+	properties map[string]Property
+
+	// targets contains the pending target values of properties by
+	// "<nodeID>/<propertyID>".
+	targets map[string]*target
+
+	// failedTargets contains the values of targets that were not reached in
+	// time. A late value that reaches it still resolves the target.
+	failedTargets map[string]string
+	targetTimeout time.Duration
 }
 
-func New(server string) (*Broker, error) {
-	baseTopic := "homie/raumfeld-bridge"
+// Device states as defined by the Homie convention.
+const (
+	StateInit         = "init"
+	StateReady        = "ready"
+	StateDisconnected = "disconnected"
+	StateSleeping     = "sleeping"
+	StateLost         = "lost"
+	StateAlert        = "alert"
+)
 
-	opts := mqtt.NewClientOptions()
-	opts.AddBroker(server)
-	opts.SetAutoReconnect(true)
-	opts.SetWill(path.Join(baseTopic, "$state"), "lost", QOSAtLeastOnce, true)
+const (
+	DefaultRoot     = "homie"
+	DefaultDeviceID = "raumfeld-bridge"
+)
 
-	client := mqtt.NewClient(opts)
-	token := client.Connect()
-	token.Wait()
-	if token.Error() != nil {
-		return nil, token.Error()
+var idPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// ValidateID checks whether the given device, node or property ID complies
+// with the Homie topic ID rules.
+func ValidateID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("invalid homie ID %#v, it must only contain lowercase letters, digits and hyphens and must not start or end with a hyphen", id)
 	}
 
+	return nil
+}
+
+// ValidateRoot checks whether the given root topic can be used as base for
+// the device topics.
+func ValidateRoot(root string) error {
+	if root == "" || strings.HasPrefix(root, "/") || strings.HasSuffix(root, "/") {
+		return fmt.Errorf("invalid homie root %#v, it must not be empty or start or end with a slash", root)
+	}
+
+	if strings.ContainsAny(root, "+#$") {
+		return fmt.Errorf("invalid homie root %#v, it must not contain wildcards or a dollar sign", root)
+	}
+
+	return nil
+}
+
+func New(conn mqttconn.Options, root, deviceID string) (*Broker, error) {
+	err := errors.Join(ValidateRoot(root), ValidateID(deviceID))
+	if err != nil {
+		return nil, err
+	}
+
+	return newBroker(conn, path.Join(root, deviceID), false)
+}
+
+func newBroker(conn mqttconn.Options, baseTopic string, homie5 bool) (*Broker, error) {
+	opts, err := conn.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.SetWill(path.Join(baseTopic, "$state"), StateLost, QOSAtLeastOnce, true)
+
 	broker := &Broker{
-		client:    client,
-		baseTopic: baseTopic,
+		baseTopic:  baseTopic,
+		published:  map[string]string{},
+		homie5:     homie5,
+		nodes:      map[string]Node{},
+		properties: map[string]Property{},
+
+		targets:       map[string]*target{},
+		failedTargets: map[string]string{},
+		targetTimeout: DefaultTargetTimeout,
 	}
 
-	_ = client.Subscribe(path.Join(baseTopic, "+", "+", "set"), QOSAtMostOnce, broker.handleAction)
-	// not sure what to do which the token
+	broker.actions = mqttconn.NewQueue(broker.handleAction)
+	opts.SetOnConnectHandler(broker.onConnect)
+
+	client, err := mqttconn.Connect(opts)
+	if err != nil {
+		broker.actions.Close()
+		return nil, err
+	}
+	broker.client = client
+
+	err = broker.subscribe(client)
+	if err != nil {
+		client.Disconnect(1000)
+		broker.actions.Close()
+		return nil, err
+	}
 
 	return broker, nil
 }
 
+func (b *Broker) subscribe(client mqtt.Client) error {
+	topic := path.Join(b.baseTopic, "+", "+", "set")
+
+	token := client.Subscribe(topic, QOSAtMostOnce, b.actions.Handle)
+	token.Wait()
+	if token.Error() != nil {
+		return fmt.Errorf("subscribe %q: %w", topic, token.Error())
+	}
+
+	return nil
+}
+
+// onConnect gets called after every (re)connect. The initial connect is
+// handled by New. After a reconnect the subscription might be gone and the
+// broker published the will, so everything needs to be restored.
+func (b *Broker) onConnect(client mqtt.Client) {
+	b.mu.Lock()
+	reconnect := b.connected
+	b.connected = true
+	b.mu.Unlock()
+
+	if !reconnect {
+		return
+	}
+
+	logrus.Info("reconnected to MQTT broker, restoring subscriptions and topics")
+
+	err := b.subscribe(client)
+	if err != nil {
+		logrus.WithError(err).Error("failed to restore subscription after reconnect")
+	}
+
+	err = b.republish(client)
+	if err != nil {
+		logrus.WithError(err).Error("failed to restore topics after reconnect")
+	}
+}
+
+// republish publishes all retained topics again. The device is in the init
+// state while doing so, as required by the convention.
+func (b *Broker) republish(client mqtt.Client) error {
+	stateTopic := path.Join(b.baseTopic, "$state")
+
+	b.mu.RLock()
+	messages := make(map[string]string, len(b.published))
+	for topic, message := range b.published {
+		messages[topic] = message
+	}
+	state, found := messages[stateTopic]
+	b.mu.RUnlock()
+
+	if !found {
+		return nil
+	}
+
+	errs := []error{publishRetained(client, stateTopic, StateInit)}
+	for topic, message := range messages {
+		if topic == stateTopic {
+			continue
+		}
+		errs = append(errs, publishRetained(client, topic, message))
+	}
+	errs = append(errs, publishRetained(client, stateTopic, state))
+
+	return errors.Join(errs...)
+}
+
+// SetState publishes the device state, if it changed.
+func (b *Broker) SetState(state string) error {
+	b.mu.Lock()
+	changed := b.state != state
+	b.state = state
+	b.mu.Unlock()
+
+	if !changed {
+		return nil
+	}
+
+	if b.homie5 {
+		return b.setStateV5(state)
+	}
+
+	return b.publish("$state", state)
+}
+
+func (b *Broker) SetActionHandler(handler func(string, string, string) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.actionHandler = handler
+}
+
 func (b *Broker) handleAction(client mqtt.Client, message mqtt.Message) {
-	if b.ActionHandler == nil {
+	b.mu.RLock()
+	actionHandler := b.actionHandler
+	b.mu.RUnlock()
+
+	if actionHandler == nil {
 		message.Ack()
 		return
 	}
@@ -66,15 +254,50 @@ func (b *Broker) handleAction(client mqtt.Client, message mqtt.Message) {
 		return
 	}
 
-	err := b.ActionHandler(nodeID, propertyID, string(message.Payload()))
+	payload := string(message.Payload())
+
+	err := b.validateAction(nodeID, propertyID, payload)
+	if err != nil {
+		logrus.
+			WithError(err).
+			WithField("topic", message.Topic()).
+			Warn("rejected invalid set command")
+		return
+	}
+
+	err = b.setTarget(nodeID, propertyID, payload)
+	if err != nil {
+		logrus.WithError(err).Error("failed to publish target")
+	}
+
+	err = actionHandler(nodeID, propertyID, payload)
 	if err != nil {
 		logrus.Error(err)
+		b.cancelTarget(nodeID, propertyID, err)
 		return
 	}
 
 	message.Ack()
 }
 
+// validateAction checks whether the set command targets a settable property
+// and whether the payload matches its data type and format.
+func (b *Broker) validateAction(nodeID, propertyID, payload string) error {
+	b.mu.RLock()
+	property, found := b.properties[path.Join(nodeID, propertyID)]
+	b.mu.RUnlock()
+
+	if !found {
+		return fmt.Errorf("property %#v of node %#v does not exist", propertyID, nodeID)
+	}
+
+	if !property.Settable {
+		return fmt.Errorf("property %#v of node %#v is not settable", propertyID, nodeID)
+	}
+
+	return property.ValidateValue(payload)
+}
+
 func (b *Broker) MustClose() {
 	err := b.Close()
 	if err != nil {
@@ -83,34 +306,120 @@ func (b *Broker) MustClose() {
 }
 
 func (b *Broker) Close() error {
-	err := b.publish("$state", "disconnected")
+	err := b.SetState(StateDisconnected)
 	if err != nil {
 		return err
 	}
 
 	b.client.Disconnect(1000)
+	b.actions.Close()
 	return nil
 }
 
+// PublishDevice publishes the device attributes and removes all previously
+// published nodes, that are not part of the device anymore.
 func (d *Broker) PublishDevice(device Device) error {
+	d.forgetRemoved(device)
+
+	if d.homie5 {
+		return d.publishDescription(device)
+	}
+
+	nodeIDs := toSet(device.NodeIDs)
+
 	return errors.Join(
 		d.publish("$homie", "4.0.0"),
 		d.publish("$name", device.Name),
-		d.publish("$state", "ready"),
 		d.publish("$implementation", device.Implementation),
 		d.publish("$nodes", strings.Join(device.NodeIDs, ",")),
+		d.clearWhere(func(nodeID, propertyID string) bool {
+			_, found := nodeIDs[nodeID]
+			return !found
+		}),
 	)
 }
 
+// forgetRemoved drops all registered nodes and properties, that are not part
+// of the device anymore, so set commands for them get rejected.
+func (d *Broker) forgetRemoved(device Device) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	nodeIDs := toSet(device.NodeIDs)
+	for nodeID := range d.nodes {
+		if _, found := nodeIDs[nodeID]; !found {
+			delete(d.nodes, nodeID)
+		}
+	}
+
+	for key, property := range d.properties {
+		node, found := d.nodes[property.NodeID]
+		if !found {
+			delete(d.properties, key)
+			continue
+		}
+
+		if _, found := toSet(node.PropertyIDs)[property.PropertyID]; !found {
+			delete(d.properties, key)
+		}
+	}
+
+	// Otherwise the timers would report problems for nodes that are gone.
+	for key, t := range d.targets {
+		if _, found := d.properties[key]; !found {
+			t.timer.Stop()
+			delete(d.targets, key)
+		}
+	}
+
+	for key := range d.failedTargets {
+		if _, found := d.properties[key]; !found {
+			delete(d.failedTargets, key)
+		}
+	}
+}
+
+// PublishNode publishes the node attributes and removes all previously
+// published properties, that are not part of the node anymore.
 func (d *Broker) PublishNode(node Node) error {
+	d.mu.Lock()
+	d.nodes[node.NodeID] = node
+	d.mu.Unlock()
+
+	if d.homie5 {
+		return nil
+	}
+
+	propertyIDs := toSet(node.PropertyIDs)
+
 	return errors.Join(
 		d.publish(path.Join(node.NodeID, "$name"), node.Name),
 		d.publish(path.Join(node.NodeID, "$type"), node.Type),
 		d.publish(path.Join(node.NodeID, "$properties"), strings.Join(node.PropertyIDs, ",")),
+		d.clearWhere(func(nodeID, propertyID string) bool {
+			if nodeID != node.NodeID || propertyID == "" {
+				return false
+			}
+			_, found := propertyIDs[propertyID]
+			return !found
+		}),
 	)
 }
 
 func (d *Broker) PublishProperty(property Property) error {
+	err := property.Validate()
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.properties[path.Join(property.NodeID, property.PropertyID)] = property
+	d.mu.Unlock()
+
+	if d.homie5 {
+		return nil
+	}
+
 	prefix := path.Join(property.NodeID, property.PropertyID)
 	return errors.Join(
 		d.publish(path.Join(prefix, "$name"), property.Name),
@@ -122,17 +431,105 @@ func (d *Broker) PublishProperty(property Property) error {
 	)
 }
 
+// PublishValue publishes the value of a property and clears its $target, if
+// the value reached it.
 func (d *Broker) PublishValue(nodeID, propertyID string, value any) error {
-	return d.publish(path.Join(nodeID, propertyID), fmt.Sprint(value))
+	message := FormatValue(value)
+
+	return errors.Join(
+		d.publish(path.Join(nodeID, propertyID), message),
+		d.confirmTarget(nodeID, propertyID, message),
+	)
 }
 
 func (d *Broker) publish(topic string, message string) error {
 	fullTopic := path.Join(d.baseTopic, topic)
 
-	token := d.client.Publish(fullTopic, QOSAtLeastOnce, true, message)
+	d.mu.Lock()
+	d.published[fullTopic] = message
+	d.mu.Unlock()
+
+	return publishRetained(d.client, fullTopic, message)
+}
+
+// clear removes the retained message of a single topic, if it was published
+// before.
+func (d *Broker) clear(topic string) error {
+	fullTopic := path.Join(d.baseTopic, topic)
+
+	d.mu.Lock()
+	_, found := d.published[fullTopic]
+	delete(d.published, fullTopic)
+	d.mu.Unlock()
+
+	if !found {
+		return nil
+	}
+
+	return publishRetained(d.client, fullTopic, "")
+}
+
+// clearWhere removes the retained messages of all published topics that
+// match. The property ID is empty for node attributes.
+func (d *Broker) clearWhere(match func(nodeID, propertyID string) bool) error {
+	d.mu.Lock()
+	topics := selectTopics(d.published, d.baseTopic, match)
+	for _, topic := range topics {
+		delete(d.published, topic)
+	}
+	d.mu.Unlock()
+
+	errs := []error{}
+	for _, topic := range topics {
+		// An empty retained message deletes the retained message on the
+		// broker.
+		errs = append(errs, publishRetained(d.client, topic, ""))
+	}
+
+	return errors.Join(errs...)
+}
+
+func selectTopics(published map[string]string, baseTopic string, match func(nodeID, propertyID string) bool) []string {
+	result := []string{}
+	for topic := range published {
+		rel, found := strings.CutPrefix(topic, baseTopic+"/")
+		if !found {
+			continue
+		}
+
+		segments := strings.Split(rel, "/")
+		if strings.HasPrefix(segments[0], "$") {
+			// device attribute
+			continue
+		}
+
+		nodeID, propertyID := segments[0], ""
+		if len(segments) > 1 && !strings.HasPrefix(segments[1], "$") {
+			propertyID = segments[1]
+		}
+
+		if match(nodeID, propertyID) {
+			result = append(result, topic)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+func toSet(values []string) map[string]struct{} {
+	result := map[string]struct{}{}
+	for _, v := range values {
+		result[v] = struct{}{}
+	}
+	return result
+}
+
+func publishRetained(client mqtt.Client, topic string, message string) error {
+	token := client.Publish(topic, QOSAtLeastOnce, true, message)
 	token.Wait()
 	if token.Error() != nil {
-		return fmt.Errorf("publish %q: %w", fullTopic, token.Error())
+		return fmt.Errorf("publish %q: %w", topic, token.Error())
 	}
 
 	return nil
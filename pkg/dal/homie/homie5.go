@@ -0,0 +1,153 @@
+package homie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+
+	"github.com/svenwltr/devilctl/pkg/dal/mqttconn"
+)
+
+// AlertProblems is the alert ID that gets used by Homie 5 devices instead of
+// the alert state, which got removed from the convention.
+const AlertProblems = "problems"
+
+// NewV5 creates a broker that implements the Homie 5 convention. The device
+// is published below "<root>/5/<deviceID>" and all attributes are combined
+// into a single $description document.
+func NewV5(conn mqttconn.Options, root, deviceID string) (*Broker, error) {
+	err := errors.Join(ValidateRoot(root), ValidateID(deviceID))
+	if err != nil {
+		return nil, err
+	}
+
+	return newBroker(conn, path.Join(root, "5", deviceID), true)
+}
+
+type description struct {
+	Homie   string                     `json:"homie"`
+	Version uint32                     `json:"version"`
+	Name    string                     `json:"name,omitempty"`
+	Nodes   map[string]nodeDescription `json:"nodes"`
+}
+
+type nodeDescription struct {
+	Name       string                         `json:"name,omitempty"`
+	Type       string                         `json:"type,omitempty"`
+	Properties map[string]propertyDescription `json:"properties"`
+}
+
+type propertyDescription struct {
+	Name     string `json:"name,omitempty"`
+	DataType string `json:"datatype"`
+	Format   string `json:"format,omitempty"`
+	Settable bool   `json:"settable,omitempty"`
+	Retained *bool  `json:"retained,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+}
+
+// buildDescription assembles the Homie 5 $description document from the
+// registered nodes and properties. Only nodes and properties that are listed
+// in the device and node are included. The version is derived from the
+// content, so it only changes if the description changes.
+func buildDescription(device Device, nodes map[string]Node, properties map[string]Property) ([]byte, error) {
+	desc := description{
+		Homie: "5.0",
+		Name:  device.Name,
+		Nodes: map[string]nodeDescription{},
+	}
+
+	for _, nodeID := range device.NodeIDs {
+		node, found := nodes[nodeID]
+		if !found {
+			return nil, fmt.Errorf("node %#v was not published", nodeID)
+		}
+
+		nodeDesc := nodeDescription{
+			Name:       node.Name,
+			Type:       node.Type,
+			Properties: map[string]propertyDescription{},
+		}
+
+		for _, propertyID := range node.PropertyIDs {
+			property, found := properties[path.Join(nodeID, propertyID)]
+			if !found {
+				return nil, fmt.Errorf("property %#v of node %#v was not published", propertyID, nodeID)
+			}
+
+			propertyDesc := propertyDescription{
+				Name:     property.Name,
+				DataType: property.DataType,
+				Format:   property.Format,
+				Settable: property.Settable,
+				Unit:     property.Unit,
+			}
+			if !property.Retained {
+				// Properties are retained by default in Homie 5.
+				propertyDesc.Retained = &property.Retained
+			}
+
+			nodeDesc.Properties[propertyID] = propertyDesc
+		}
+
+		desc.Nodes[nodeID] = nodeDesc
+	}
+
+	content, err := json.Marshal(desc)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := fnv.New32a()
+	hash.Write(content)
+	desc.Version = hash.Sum32()
+
+	return json.Marshal(desc)
+}
+
+// publishDescription publishes the $description document of a Homie 5
+// device and removes all previously published values, that are not part of
+// the device anymore.
+func (d *Broker) publishDescription(device Device) error {
+	d.mu.RLock()
+	content, err := buildDescription(device, d.nodes, d.properties)
+	propertyIDs := map[string]map[string]struct{}{}
+	for _, nodeID := range device.NodeIDs {
+		propertyIDs[nodeID] = toSet(d.nodes[nodeID].PropertyIDs)
+	}
+	d.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("build homie description: %w", err)
+	}
+
+	return errors.Join(
+		d.publish("$description", string(content)),
+		d.clearWhere(func(nodeID, propertyID string) bool {
+			properties, found := propertyIDs[nodeID]
+			if !found {
+				return true
+			}
+			_, found = properties[propertyID]
+			return !found
+		}),
+	)
+}
+
+// setStateV5 publishes the device state of a Homie 5 device. The alert state
+// does not exist anymore, therefore the device stays ready and an alert gets
+// published instead.
+func (b *Broker) setStateV5(state string) error {
+	alertTopic := path.Join("$alert", AlertProblems)
+
+	errs := []error{}
+	if state == StateAlert {
+		state = StateReady
+		errs = append(errs, b.publish(alertTopic, "At least one speaker has a problem."))
+	} else {
+		errs = append(errs, b.clear(alertTopic))
+	}
+
+	return errors.Join(append(errs, b.publish("$state", state))...)
+}
@@ -0,0 +1,255 @@
+package homie
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateID(t *testing.T) {
+	for _, id := range []string{"raumfeld-bridge", "office", "cd19c884-dcea-4368-bcb2-fa70d3165631", "a1"} {
+		require.NoError(t, ValidateID(id), id)
+	}
+
+	for _, id := range []string{"", "-office", "office-", "Office", "my_office", "office--2", "office/2"} {
+		require.Error(t, ValidateID(id), id)
+	}
+}
+
+func TestValidateRoot(t *testing.T) {
+	for _, root := range []string{"homie", "house/homie"} {
+		require.NoError(t, ValidateRoot(root), root)
+	}
+
+	for _, root := range []string{"", "/homie", "homie/", "homie/+", "homie/#", "$homie"} {
+		require.Error(t, ValidateRoot(root), root)
+	}
+}
+
+func TestSelectTopics(t *testing.T) {
+	published := map[string]string{
+		"homie/bridge/$nodes":                "kitchen,bath",
+		"homie/bridge/kitchen/$name":         "Kitchen",
+		"homie/bridge/kitchen/volume":        "0.1",
+		"homie/bridge/kitchen/volume/$name":  "Volume",
+		"homie/bridge/kitchen/treble":        "0",
+		"homie/bridge/kitchen/treble/$name":  "Treble",
+		"homie/bridge/bath/$name":            "Bath",
+		"homie/bridge/bath/volume":           "0.2",
+		"homie/other/kitchen/volume/$name":   "Volume",
+		"homie/bridge-2/kitchen/volume/name": "Volume",
+	}
+
+	staleNodes := selectTopics(published, "homie/bridge", func(nodeID, propertyID string) bool {
+		return nodeID == "bath"
+	})
+	require.Equal(t, []string{
+		"homie/bridge/bath/$name",
+		"homie/bridge/bath/volume",
+	}, staleNodes)
+
+	staleProperties := selectTopics(published, "homie/bridge", func(nodeID, propertyID string) bool {
+		return nodeID == "kitchen" && propertyID == "treble"
+	})
+	require.Equal(t, []string{
+		"homie/bridge/kitchen/treble",
+		"homie/bridge/kitchen/treble/$name",
+	}, staleProperties)
+}
+
+func TestBuildDescription(t *testing.T) {
+	device := Device{Name: "Bridge", NodeIDs: []string{"kitchen"}}
+	nodes := map[string]Node{
+		"kitchen": {NodeID: "kitchen", Name: "Kitchen", Type: "Speaker", PropertyIDs: []string{"volume", "seek"}},
+		"bath":    {NodeID: "bath", Name: "Bath", Type: "Speaker"},
+	}
+	properties := map[string]Property{
+		"kitchen/volume": {NodeID: "kitchen", PropertyID: "volume", Name: "Volume", DataType: "float", Format: "0:1", Settable: true, Retained: true},
+		"kitchen/seek":   {NodeID: "kitchen", PropertyID: "seek", Name: "Seek", DataType: "integer", Settable: true, Unit: "s"},
+	}
+
+	content, err := buildDescription(device, nodes, properties)
+	require.NoError(t, err)
+
+	var desc map[string]any
+	require.NoError(t, json.Unmarshal(content, &desc))
+	require.NotZero(t, desc["version"])
+	delete(desc, "version")
+
+	require.Equal(t, map[string]any{
+		"homie": "5.0",
+		"name":  "Bridge",
+		"nodes": map[string]any{
+			"kitchen": map[string]any{
+				"name": "Kitchen",
+				"type": "Speaker",
+				"properties": map[string]any{
+					"volume": map[string]any{"name": "Volume", "datatype": "float", "format": "0:1", "settable": true},
+					"seek":   map[string]any{"name": "Seek", "datatype": "integer", "settable": true, "retained": false, "unit": "s"},
+				},
+			},
+		},
+	}, desc)
+
+	again, err := buildDescription(device, nodes, properties)
+	require.NoError(t, err)
+	require.Equal(t, content, again)
+
+	device.NodeIDs = append(device.NodeIDs, "office")
+	_, err = buildDescription(device, nodes, properties)
+	require.Error(t, err)
+}
+
+func TestTargetReached(t *testing.T) {
+	require.True(t, targetReached("true", "true"))
+	require.True(t, targetReached("0.5", "0.5"))
+	require.True(t, targetReached("0.555", "0.56"))
+	require.True(t, targetReached("0.555", "0.55"))
+
+	require.False(t, targetReached("true", "false"))
+	require.False(t, targetReached("0.5", "0.52"))
+	require.False(t, targetReached("0.5", "half"))
+}
+
+func TestPropertyValidate(t *testing.T) {
+	for _, p := range []Property{
+		{DataType: DataTypeFloat, Format: "0:1"},
+		{DataType: DataTypeFloat},
+		{DataType: DataTypeInteger, Format: "0:"},
+		{DataType: DataTypeInteger, Format: ":100"},
+		{DataType: DataTypeBoolean},
+		{DataType: DataTypeString},
+		{DataType: DataTypeEnum, Format: "ACTIVE,MANUAL_STANDBY"},
+		{DataType: DataTypeColor, Format: "rgb"},
+		{DataType: DataTypeColor, Format: "hsv"},
+		{DataType: DataTypeDateTime},
+		{DataType: DataTypeDuration},
+	} {
+		require.NoError(t, p.Validate(), p)
+	}
+
+	for _, p := range []Property{
+		{DataType: "number"},
+		{DataType: DataTypeFloat, Format: "1:0"},
+		{DataType: DataTypeFloat, Format: "0-1"},
+		{DataType: DataTypeInteger, Format: "0:1.5"},
+		{DataType: DataTypeBoolean, Format: "0:1"},
+		{DataType: DataTypeEnum},
+		{DataType: DataTypeEnum, Format: "a,,b"},
+		{DataType: DataTypeColor},
+		{DataType: DataTypeColor, Format: "cmyk"},
+		{DataType: DataTypeDuration, Format: "s"},
+	} {
+		require.Error(t, p.Validate(), p)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	require.Equal(t, "0.5", FormatValue(0.5))
+	require.Equal(t, "true", FormatValue(true))
+	require.Equal(t, "2023-08-04T17:23:56Z", FormatValue(time.Date(2023, 8, 4, 17, 23, 56, 0, time.UTC)))
+	require.Equal(t, "PT0S", FormatValue(time.Duration(0)))
+	require.Equal(t, "PT1H2M3S", FormatValue(time.Hour+2*time.Minute+3*time.Second))
+	require.Equal(t, "PT5M", FormatValue(5*time.Minute))
+	require.Equal(t, "PT0S", FormatValue(-30*time.Second))
+
+	for _, d := range []time.Duration{0, 90 * time.Second, 2 * time.Hour, -time.Minute} {
+		require.NoError(t, Property{DataType: DataTypeDuration}.ValidateValue(FormatValue(d)), d)
+	}
+}
+
+func TestPropertyValidateValue(t *testing.T) {
+	volume := Property{DataType: DataTypeFloat, Format: "0:1"}
+	seek := Property{DataType: DataTypeInteger, Format: "0:"}
+	onoff := Property{DataType: DataTypeBoolean}
+	transport := Property{DataType: DataTypeEnum, Format: "play,pause,stop"}
+	rgb := Property{DataType: DataTypeColor, Format: ColorFormatRGB}
+	hsv := Property{DataType: DataTypeColor, Format: ColorFormatHSV}
+	datetime := Property{DataType: DataTypeDateTime}
+	duration := Property{DataType: DataTypeDuration}
+
+	for _, tc := range []struct {
+		property Property
+		payload  string
+		valid    bool
+	}{
+		{volume, "0", true},
+		{volume, "0.35", true},
+		{volume, "1", true},
+		{volume, "1.5", false},
+		{volume, "-0.1", false},
+		{volume, "NaN", false},
+		{volume, "loud", false},
+		{seek, "90", true},
+		{seek, "-1", false},
+		{seek, "1.5", false},
+		{onoff, "true", true},
+		{onoff, "false", true},
+		{onoff, "on", false},
+		{onoff, "TRUE", false},
+		{transport, "pause", true},
+		{transport, "rewind", false},
+		{rgb, "255,128,0", true},
+		{rgb, "256,0,0", false},
+		{rgb, "0,0", false},
+		{hsv, "360,100,100", true},
+		{hsv, "180,101,0", false},
+		{datetime, "2023-08-04T17:23:56Z", true},
+		{datetime, "yesterday", false},
+		{duration, "PT1H2M3S", true},
+		{duration, "PT", false},
+		{duration, "90", false},
+	} {
+		err := tc.property.ValidateValue(tc.payload)
+		if tc.valid {
+			require.NoError(t, err, tc.payload)
+		} else {
+			require.Error(t, err, tc.payload)
+		}
+	}
+}
+
+func TestLateTargetConfirmation(t *testing.T) {
+	b := &Broker{
+		published:     map[string]string{},
+		nodes:         map[string]Node{},
+		properties:    map[string]Property{},
+		targets:       map[string]*target{},
+		failedTargets: map[string]string{"kitchen/volume": "0.5"},
+	}
+
+	resolved := []error{}
+	b.SetTargetHandler(func(nodeID, propertyID string, err error) {
+		resolved = append(resolved, err)
+	})
+
+	require.NoError(t, b.confirmTarget("kitchen", "volume", "0.3"))
+	require.Empty(t, resolved)
+
+	require.NoError(t, b.confirmTarget("kitchen", "volume", "0.5"))
+	require.Equal(t, []error{nil}, resolved)
+	require.Empty(t, b.failedTargets)
+}
+
+func TestForgetRemovedStopsTargets(t *testing.T) {
+	timer := time.AfterFunc(time.Hour, func() {})
+	b := &Broker{
+		nodes: map[string]Node{
+			"kitchen": {NodeID: "kitchen", PropertyIDs: []string{"volume"}},
+		},
+		properties: map[string]Property{
+			"kitchen/volume": {NodeID: "kitchen", PropertyID: "volume"},
+		},
+		targets:       map[string]*target{"kitchen/volume": {value: "0.5", timer: timer}},
+		failedTargets: map[string]string{"kitchen/mute": "true"},
+	}
+
+	b.forgetRemoved(Device{})
+
+	require.Empty(t, b.properties)
+	require.Empty(t, b.targets)
+	require.Empty(t, b.failedTargets)
+	require.False(t, timer.Stop(), "timer should be stopped already")
+}
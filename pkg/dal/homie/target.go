@@ -0,0 +1,173 @@
+package homie
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTargetTimeout is the time a property has to reach its target value,
+// before the command is considered as failed.
+const DefaultTargetTimeout = 10 * time.Second
+
+// targetTolerance is the maximum difference between numeric values that still
+// counts as reached, since devices usually round the requested values to
+// whole percents.
+const targetTolerance = 0.015
+
+type target struct {
+	value string
+	timer *time.Timer
+}
+
+// SetTargetHandler registers a function that gets called after a property
+// either reached its target value (with a nil error) or the target timed out.
+func (b *Broker) SetTargetHandler(handler func(nodeID, propertyID string, err error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.targetHandler = handler
+}
+
+// setTarget publishes the $target attribute of a retained property after a
+// set command was accepted. The target gets cleared with the next value that
+// matches it. Non-retained properties are commands without a value, so there
+// is nothing to wait for.
+func (b *Broker) setTarget(nodeID, propertyID, value string) error {
+	key := path.Join(nodeID, propertyID)
+
+	b.mu.Lock()
+	property, found := b.properties[key]
+	current, hasCurrent := b.published[path.Join(b.baseTopic, key)]
+	if existing, ok := b.targets[key]; ok {
+		existing.timer.Stop()
+		delete(b.targets, key)
+	}
+	delete(b.failedTargets, key)
+	b.mu.Unlock()
+
+	if !found || !property.Retained {
+		return nil
+	}
+
+	if hasCurrent && targetReached(value, current) {
+		// The device will not send an event, if nothing changes.
+		b.resolveTarget(nodeID, propertyID, nil)
+		return b.clear(path.Join(key, "$target"))
+	}
+
+	t := &target{value: value}
+
+	b.mu.Lock()
+	b.targets[key] = t
+	t.timer = time.AfterFunc(b.targetTimeout, func() {
+		b.failTarget(nodeID, propertyID, t, fmt.Errorf(
+			"property %#v of node %#v did not reach target %#v within %v",
+			propertyID, nodeID, value, b.targetTimeout))
+	})
+	b.mu.Unlock()
+
+	return b.publish(path.Join(key, "$target"), value)
+}
+
+// cancelTarget clears the pending target of a property, because the command
+// failed.
+func (b *Broker) cancelTarget(nodeID, propertyID string, err error) {
+	b.mu.RLock()
+	t := b.targets[path.Join(nodeID, propertyID)]
+	b.mu.RUnlock()
+
+	if t != nil {
+		b.failTarget(nodeID, propertyID, t, err)
+	}
+}
+
+func (b *Broker) failTarget(nodeID, propertyID string, t *target, err error) {
+	key := path.Join(nodeID, propertyID)
+
+	b.mu.Lock()
+	active := b.targets[key] == t
+	if active {
+		t.timer.Stop()
+		delete(b.targets, key)
+		b.failedTargets[key] = t.value
+	}
+	b.mu.Unlock()
+
+	if !active {
+		// Another command or value superseded the target already.
+		return
+	}
+
+	clearErr := b.clear(path.Join(key, "$target"))
+	if clearErr != nil {
+		logrus.WithError(clearErr).Error("failed to clear target")
+	}
+
+	b.resolveTarget(nodeID, propertyID, err)
+}
+
+// confirmTarget clears the target of a property, if the given value reached
+// it. A value that reaches a target after it failed resolves it as well, so
+// the failure does not stick forever.
+func (b *Broker) confirmTarget(nodeID, propertyID, value string) error {
+	key := path.Join(nodeID, propertyID)
+
+	b.mu.Lock()
+	t, found := b.targets[key]
+	reached := found && targetReached(t.value, value)
+	if reached {
+		t.timer.Stop()
+		delete(b.targets, key)
+	}
+
+	failedValue, failed := b.failedTargets[key]
+	recovered := failed && targetReached(failedValue, value)
+	if recovered {
+		delete(b.failedTargets, key)
+	}
+	b.mu.Unlock()
+
+	if recovered {
+		b.resolveTarget(nodeID, propertyID, nil)
+	}
+
+	if !reached {
+		return nil
+	}
+
+	b.resolveTarget(nodeID, propertyID, nil)
+	return b.clear(path.Join(key, "$target"))
+}
+
+func (b *Broker) resolveTarget(nodeID, propertyID string, err error) {
+	b.mu.RLock()
+	handler := b.targetHandler
+	b.mu.RUnlock()
+
+	if handler != nil {
+		handler(nodeID, propertyID, err)
+	}
+}
+
+func targetReached(target, value string) bool {
+	if target == value {
+		return true
+	}
+
+	targetNumber, err := strconv.ParseFloat(target, 64)
+	if err != nil {
+		return false
+	}
+
+	valueNumber, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	return math.Abs(targetNumber-valueNumber) <= targetTolerance
+}
@@ -1,5 +1,14 @@
 package homie
 
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
 type Device struct {
 	Name           string
 	Implementation string
@@ -25,3 +34,252 @@ type Property struct {
 	Retained bool
 	Unit     string
 }
+
+// Data types of properties as defined by the Homie convention.
+const (
+	DataTypeInteger  = "integer"
+	DataTypeFloat    = "float"
+	DataTypeBoolean  = "boolean"
+	DataTypeString   = "string"
+	DataTypeEnum     = "enum"
+	DataTypeColor    = "color"
+	DataTypeDateTime = "datetime"
+	DataTypeDuration = "duration"
+)
+
+// Color formats as defined by the Homie convention.
+const (
+	ColorFormatRGB = "rgb"
+	ColorFormatHSV = "hsv"
+)
+
+// Validate checks whether the format of the property matches its data type.
+func (p Property) Validate() error {
+	err := validateFormat(p.DataType, p.Format)
+	if err != nil {
+		return fmt.Errorf("invalid property %#v of node %#v: %w", p.PropertyID, p.NodeID, err)
+	}
+
+	return nil
+}
+
+func validateFormat(dataType, format string) error {
+	switch dataType {
+	case DataTypeInteger, DataTypeFloat:
+		if format == "" {
+			return nil
+		}
+
+		_, _, err := ParseRange(dataType, format)
+		return err
+
+	case DataTypeEnum:
+		if format == "" {
+			return fmt.Errorf("enum requires a format with the allowed values")
+		}
+
+		for _, value := range strings.Split(format, ",") {
+			if value == "" {
+				return fmt.Errorf("enum format %#v contains an empty value", format)
+			}
+		}
+		return nil
+
+	case DataTypeColor:
+		if format != ColorFormatRGB && format != ColorFormatHSV {
+			return fmt.Errorf("color format must be %#v or %#v, got %#v", ColorFormatRGB, ColorFormatHSV, format)
+		}
+		return nil
+
+	case DataTypeBoolean, DataTypeString, DataTypeDateTime, DataTypeDuration:
+		if format != "" {
+			return fmt.Errorf("data type %#v does not support a format, got %#v", dataType, format)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown data type %#v", dataType)
+	}
+}
+
+// ParseRange parses the "<min>:<max>" format of numeric properties. Both ends
+// are optional and nil, if not specified.
+func ParseRange(dataType, format string) (*float64, *float64, error) {
+	minValue, maxValue, ok := strings.Cut(format, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("range format %#v must be in the form <min>:<max>", format)
+	}
+
+	parse := func(value string) (*float64, error) {
+		if value == "" {
+			return nil, nil
+		}
+
+		if dataType == DataTypeInteger {
+			number, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer %#v in range format: %w", value, err)
+			}
+			result := float64(number)
+			return &result, nil
+		}
+
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %#v in range format: %w", value, err)
+		}
+		return &number, nil
+	}
+
+	minNumber, err := parse(minValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxNumber, err := parse(maxValue)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if minNumber != nil && maxNumber != nil && *minNumber > *maxNumber {
+		return nil, nil, fmt.Errorf("range format %#v has a minimum larger than the maximum", format)
+	}
+
+	return minNumber, maxNumber, nil
+}
+
+var durationPattern = regexp.MustCompile(`^PT(\d+H)?(\d+M)?(\d+S)?$`)
+
+// ValidateValue checks whether the payload of a set command is a valid value
+// for the property.
+func (p Property) ValidateValue(payload string) error {
+	switch p.DataType {
+	case DataTypeBoolean:
+		if payload != "true" && payload != "false" {
+			return fmt.Errorf("boolean must be \"true\" or \"false\", got %#v", payload)
+		}
+		return nil
+
+	case DataTypeInteger:
+		number, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %#v", payload)
+		}
+		return p.validateRange(float64(number), payload)
+
+	case DataTypeFloat:
+		number, err := strconv.ParseFloat(payload, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("invalid float %#v", payload)
+		}
+		return p.validateRange(number, payload)
+
+	case DataTypeEnum:
+		for _, value := range strings.Split(p.Format, ",") {
+			if payload == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %#v is not one of %#v", payload, p.Format)
+
+	case DataTypeColor:
+		return validateColor(p.Format, payload)
+
+	case DataTypeDateTime:
+		_, err := time.Parse(time.RFC3339, payload)
+		if err != nil {
+			return fmt.Errorf("invalid datetime %#v", payload)
+		}
+		return nil
+
+	case DataTypeDuration:
+		if payload == "PT" || !durationPattern.MatchString(payload) {
+			return fmt.Errorf("invalid duration %#v", payload)
+		}
+		return nil
+
+	case DataTypeString:
+		return nil
+
+	default:
+		return fmt.Errorf("unknown data type %#v", p.DataType)
+	}
+}
+
+func (p Property) validateRange(number float64, payload string) error {
+	if p.Format == "" {
+		return nil
+	}
+
+	minNumber, maxNumber, err := ParseRange(p.DataType, p.Format)
+	if err != nil {
+		return err
+	}
+
+	if minNumber != nil && number < *minNumber || maxNumber != nil && number > *maxNumber {
+		return fmt.Errorf("value %#v is out of range %#v", payload, p.Format)
+	}
+
+	return nil
+}
+
+func validateColor(format, payload string) error {
+	limits := []int64{255, 255, 255}
+	if format == ColorFormatHSV {
+		limits = []int64{360, 100, 100}
+	}
+
+	components := strings.Split(payload, ",")
+	if len(components) != len(limits) {
+		return fmt.Errorf("%s color must have %d components, got %#v", format, len(limits), payload)
+	}
+
+	for i, component := range components {
+		number, err := strconv.ParseInt(component, 10, 64)
+		if err != nil || number < 0 || number > limits[i] {
+			return fmt.Errorf("invalid %s color %#v", format, payload)
+		}
+	}
+
+	return nil
+}
+
+// FormatValue converts a property value into its payload. Times are encoded
+// as ISO 8601 date times and durations as ISO 8601 durations, as required by
+// the datetime and duration data types. Negative durations are clamped to
+// zero.
+func FormatValue(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case time.Duration:
+		return formatDuration(v)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		// Homie durations cannot be negative.
+		d = 0
+	}
+
+	seconds := int64(d.Round(time.Second) / time.Second)
+
+	hours, seconds := seconds/3600, seconds%3600
+	minutes, seconds := seconds/60, seconds%60
+
+	result := "PT"
+	if hours > 0 {
+		result += fmt.Sprintf("%dH", hours)
+	}
+	if minutes > 0 {
+		result += fmt.Sprintf("%dM", minutes)
+	}
+	if seconds > 0 || hours == 0 && minutes == 0 {
+		result += fmt.Sprintf("%dS", seconds)
+	}
+
+	return result
+}
@@ -0,0 +1,130 @@
+package mqttconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Options contains the connection settings, that are shared between all MQTT
+// conventions.
+type Options struct {
+	Server string
+
+	Username     string
+	Password     string
+	PasswordFile string
+
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	ClientID     string
+	KeepAlive    time.Duration
+	CleanSession bool
+}
+
+// ClientOptions converts the options into paho client options. The caller
+// still needs to set convention specific options like the will.
+func (o Options) ClientOptions() (*mqtt.ClientOptions, error) {
+	if !o.CleanSession && o.ClientID == "" {
+		return nil, fmt.Errorf("a persistent session requires a client ID")
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(o.Server)
+	opts.SetAutoReconnect(true)
+	opts.SetCleanSession(o.CleanSession)
+
+	if o.ClientID != "" {
+		opts.SetClientID(o.ClientID)
+	}
+
+	if o.KeepAlive > 0 {
+		opts.SetKeepAlive(o.KeepAlive)
+	}
+
+	password, err := o.password()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Username != "" {
+		opts.SetUsername(o.Username)
+		opts.SetPassword(password)
+	}
+
+	tlsConfig, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	return opts, nil
+}
+
+func (o Options) password() (string, error) {
+	if o.PasswordFile == "" {
+		return o.Password, nil
+	}
+
+	data, err := os.ReadFile(o.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("read password file: %w", err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func (o Options) tlsConfig() (*tls.Config, error) {
+	if o.CAFile == "" && o.CertFile == "" && !o.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %#v", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// Connect creates a client from the options and waits for the connection.
+func Connect(opts *mqtt.ClientOptions) (mqtt.Client, error) {
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	token.Wait()
+	if token.Error() != nil {
+		return nil, token.Error()
+	}
+
+	return client, nil
+}
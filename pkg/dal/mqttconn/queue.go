@@ -0,0 +1,74 @@
+package mqttconn
+
+import (
+	"sync"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+)
+
+// QueueSize is the number of messages a Queue buffers, before it starts to
+// drop new messages.
+const QueueSize = 64
+
+// Queue processes messages one after another on a separate goroutine. paho
+// delivers ordered messages on its router goroutine, which also receives the
+// acknowledgements of publishes. A handler that publishes and waits for the
+// acknowledgement would therefore block the client, so the subscription only
+// enqueues the messages and the queue runs the actual handler.
+type Queue struct {
+	mu       sync.RWMutex
+	closed   bool
+	messages chan queuedMessage
+}
+
+type queuedMessage struct {
+	client  mqtt.Client
+	message mqtt.Message
+}
+
+// NewQueue starts a queue that passes every message to the handler in the
+// order they were received.
+func NewQueue(handler mqtt.MessageHandler) *Queue {
+	q := &Queue{
+		messages: make(chan queuedMessage, QueueSize),
+	}
+
+	go func() {
+		for m := range q.messages {
+			handler(m.client, m.message)
+		}
+	}()
+
+	return q
+}
+
+// Handle enqueues the message. It is meant to be used as subscription
+// handler and never blocks, so messages get dropped if the queue is full.
+func (q *Queue) Handle(client mqtt.Client, message mqtt.Message) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return
+	}
+
+	select {
+	case q.messages <- queuedMessage{client: client, message: message}:
+	default:
+		logrus.
+			WithField("topic", message.Topic()).
+			Warn("dropped message, because too many messages are pending")
+	}
+}
+
+// Close stops the queue after all pending messages are processed.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		close(q.messages)
+	}
+}
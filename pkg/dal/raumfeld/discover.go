@@ -13,7 +13,17 @@ const RaumfeldTypeURN = `urn:schemas-raumfeld-com:service:RaumfeldGenerator:1`
 
 const (
 	ChannelMaster = "Master"
+	ChannelLF     = "LF"
+	ChannelRF     = "RF"
 	InstanceID    = 1
+
+	// AVTransportInstanceID is the instance of the AVTransport service, which
+	// differs from the one of the RenderingControl service.
+	AVTransportInstanceID = 0
+)
+
+const (
+	SeekUnitRelTime = "REL_TIME"
 )
 
 func Discover(ctx context.Context) (map[string]Speaker, error) {
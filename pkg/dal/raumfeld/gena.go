@@ -0,0 +1,112 @@
+package raumfeld
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSubscriptionTimeout is the subscription duration that gets requested
+// from the speakers. The speaker might grant a different one.
+const DefaultSubscriptionTimeout = 30 * time.Minute
+
+// errSubscriptionGone is returned when the speaker does not know the SID
+// anymore, which usually happens after a reboot of the speaker.
+var errSubscriptionGone = errors.New("subscription is not known by speaker")
+
+func genaSubscribe(ctx context.Context, subURL, callback string) (string, time.Duration, error) {
+	r, err := http.NewRequestWithContext(ctx, "SUBSCRIBE", subURL, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("create subscribe request: %w", err)
+	}
+	r.Header.Set("NT", "upnp:event")
+	r.Header.Set("Callback", callback)
+	r.Header.Set("Timeout", formatGENATimeout(DefaultSubscriptionTimeout))
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return "", 0, fmt.Errorf("send subscribe request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("unexpected status from subscribe: %s", resp.Status)
+	}
+
+	sid := resp.Header.Get("SID")
+	if sid == "" {
+		return "", 0, fmt.Errorf("subscribe response does not contain a SID")
+	}
+
+	return sid, parseGENATimeout(resp.Header.Get("Timeout")), nil
+}
+
+func genaRenew(ctx context.Context, subURL, sid string) (time.Duration, error) {
+	r, err := http.NewRequestWithContext(ctx, "SUBSCRIBE", subURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create renew request: %w", err)
+	}
+	r.Header.Set("SID", sid)
+	r.Header.Set("Timeout", formatGENATimeout(DefaultSubscriptionTimeout))
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return 0, fmt.Errorf("send renew request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return parseGENATimeout(resp.Header.Get("Timeout")), nil
+	case http.StatusPreconditionFailed:
+		return 0, errSubscriptionGone
+	default:
+		return 0, fmt.Errorf("unexpected status from renew: %s", resp.Status)
+	}
+}
+
+func genaUnsubscribe(ctx context.Context, subURL, sid string) error {
+	r, err := http.NewRequestWithContext(ctx, "UNSUBSCRIBE", subURL, nil)
+	if err != nil {
+		return fmt.Errorf("create unsubscribe request: %w", err)
+	}
+	r.Header.Set("SID", sid)
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return fmt.Errorf("send unsubscribe request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPreconditionFailed:
+		return nil
+	default:
+		return fmt.Errorf("unexpected status from unsubscribe: %s", resp.Status)
+	}
+}
+
+func formatGENATimeout(d time.Duration) string {
+	return fmt.Sprintf("Second-%d", int(d.Seconds()))
+}
+
+// parseGENATimeout parses the Timeout header of a subscription response. It
+// falls back to the requested timeout, if the header is missing, invalid or
+// "infinite", so the subscription gets renewed regularly anyway.
+func parseGENATimeout(header string) time.Duration {
+	value, found := strings.CutPrefix(strings.TrimSpace(header), "Second-")
+	if !found {
+		return DefaultSubscriptionTimeout
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		return DefaultSubscriptionTimeout
+	}
+
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,22 @@
+package raumfeld
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGENATimeout(t *testing.T) {
+	cases := map[string]time.Duration{
+		"Second-300":  5 * time.Minute,
+		"Second-1800": 30 * time.Minute,
+		"infinite":    DefaultSubscriptionTimeout,
+		"":            DefaultSubscriptionTimeout,
+		"Second-abc":  DefaultSubscriptionTimeout,
+	}
+
+	for header, want := range cases {
+		require.Equal(t, want, parseGENATimeout(header), header)
+	}
+}
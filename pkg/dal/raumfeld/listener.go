@@ -0,0 +1,283 @@
+package raumfeld
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huin/goupnp/httpu"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SSDPAddr = "239.255.255.250:1900"
+
+	ntsAlive  = "ssdp:alive"
+	ntsUpdate = "ssdp:update"
+	ntsByebye = "ssdp:byebye"
+)
+
+type SpeakerEventType int
+
+const (
+	SpeakerAdded SpeakerEventType = iota
+	SpeakerUpdated
+	SpeakerRemoved
+)
+
+func (t SpeakerEventType) String() string {
+	switch t {
+	case SpeakerAdded:
+		return "added"
+	case SpeakerUpdated:
+		return "updated"
+	case SpeakerRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+type SpeakerEvent struct {
+	Type    SpeakerEventType
+	ID      string
+	Speaker Speaker
+}
+
+type listenerEntry struct {
+	id       string
+	location string
+	expiry   time.Time
+	loaded   bool
+}
+
+// Listener passively watches SSDP NOTIFY messages and emits events when
+// speakers appear, change their location or disappear from the network.
+// Speakers that neither renew their announcement nor say goodbye get removed
+// after their announced max-age expired.
+type Listener struct {
+	events chan SpeakerEvent
+
+	mu      sync.Mutex
+	entries map[string]*listenerEntry
+}
+
+func NewListener() *Listener {
+	return &Listener{
+		events:  make(chan SpeakerEvent, 16),
+		entries: map[string]*listenerEntry{},
+	}
+}
+
+func (l *Listener) Events() <-chan SpeakerEvent {
+	return l.events
+}
+
+func (l *Listener) Run(ctx context.Context) error {
+	addr, err := net.ResolveUDPAddr("udp4", SSDPAddr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	go l.expire(ctx)
+
+	server := httpu.Server{
+		Handler: httpu.HandlerFunc(func(r *http.Request) {
+			l.handle(ctx, r)
+		}),
+	}
+
+	err = server.Serve(conn)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+func (l *Listener) handle(ctx context.Context, r *http.Request) {
+	if r.Method != "NOTIFY" || r.Header.Get("NT") != RaumfeldTypeURN {
+		return
+	}
+
+	usn := r.Header.Get("USN")
+
+	switch r.Header.Get("NTS") {
+	case ntsAlive, ntsUpdate:
+		l.handleAlive(ctx, usn, r)
+	case ntsByebye:
+		l.remove(ctx, usn)
+	}
+}
+
+func (l *Listener) handleAlive(ctx context.Context, usn string, r *http.Request) {
+	maxAge, err := ssdpMaxAge(r.Header.Get("CACHE-CONTROL"))
+	if err != nil {
+		logrus.WithError(err).Warnf("invalid SSDP announcement of %#v", usn)
+		return
+	}
+
+	location := r.Header.Get("LOCATION")
+
+	l.mu.Lock()
+	entry, found := l.entries[usn]
+	if found && entry.location == location {
+		entry.expiry = time.Now().Add(maxAge)
+		l.mu.Unlock()
+		return
+	}
+	if !found {
+		entry = new(listenerEntry)
+		l.entries[usn] = entry
+	}
+	entry.location = location
+	entry.expiry = time.Now().Add(maxAge)
+	l.mu.Unlock()
+
+	speaker, err := l.load(ctx, location)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to load announced speaker %#v", location)
+	}
+
+	l.mu.Lock()
+	if l.entries[usn] != entry || entry.location != location {
+		// A goodbye or another announcement arrived while loading, which
+		// supersedes this one.
+		l.mu.Unlock()
+		return
+	}
+
+	if err != nil {
+		// Forget about the speaker, so the next announcement retries it.
+		delete(l.entries, usn)
+		l.mu.Unlock()
+
+		if entry.loaded {
+			l.emit(ctx, SpeakerEvent{
+				Type: SpeakerRemoved,
+				ID:   entry.id,
+			})
+		}
+		return
+	}
+
+	eventType := SpeakerAdded
+	if entry.loaded {
+		eventType = SpeakerUpdated
+	}
+	entry.id = speaker.ID()
+	entry.loaded = true
+	l.mu.Unlock()
+
+	l.emit(ctx, SpeakerEvent{
+		Type:    eventType,
+		ID:      speaker.ID(),
+		Speaker: speaker,
+	})
+}
+
+func (l *Listener) load(ctx context.Context, location string) (Speaker, error) {
+	u, err := parseLocation(location)
+	if err != nil {
+		return Speaker{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return New(ctx, u)
+}
+
+func (l *Listener) remove(ctx context.Context, usn string) {
+	l.mu.Lock()
+	entry, found := l.entries[usn]
+	delete(l.entries, usn)
+	l.mu.Unlock()
+
+	if !found || !entry.loaded {
+		return
+	}
+
+	l.emit(ctx, SpeakerEvent{
+		Type: SpeakerRemoved,
+		ID:   entry.id,
+	})
+}
+
+func (l *Listener) expire(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+
+		l.expireBefore(ctx, time.Now())
+	}
+}
+
+// expireBefore removes all speakers, whose announcement expired before the
+// given time.
+func (l *Listener) expireBefore(ctx context.Context, now time.Time) {
+	expired := []string{}
+	l.mu.Lock()
+	for usn, entry := range l.entries {
+		if now.After(entry.expiry) {
+			expired = append(expired, usn)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, usn := range expired {
+		l.remove(ctx, usn)
+	}
+}
+
+func (l *Listener) emit(ctx context.Context, event SpeakerEvent) {
+	select {
+	case <-ctx.Done():
+	case l.events <- event:
+	}
+}
+
+func ssdpMaxAge(cacheControl string) (time.Duration, error) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(strings.TrimSpace(key), "max-age") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return 0, fmt.Errorf("parse max-age %#v: %w", value, err)
+		}
+
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, fmt.Errorf("missing max-age in %#v", cacheControl)
+}
+
+func parseLocation(location string) (*url.URL, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("parse location %#v: %w", location, err)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,131 @@
+package raumfeld
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/svenwltr/devilctl/pkg/dal/raumfeld/raumfeldtest"
+)
+
+func TestSSDPMaxAge(t *testing.T) {
+	for header, want := range map[string]time.Duration{
+		"max-age=1800":               30 * time.Minute,
+		"max-age = 60":               time.Minute,
+		"MAX-AGE=5":                  5 * time.Second,
+		"no-cache, max-age=120":      2 * time.Minute,
+		`max-age=90, no-cache="ext"`: 90 * time.Second,
+	} {
+		have, err := ssdpMaxAge(header)
+		require.NoError(t, err, header)
+		require.Equal(t, want, have, header)
+	}
+
+	for _, header := range []string{"", "no-cache", "max-age=", "max-age=soon"} {
+		_, err := ssdpMaxAge(header)
+		require.Error(t, err, header)
+	}
+}
+
+func TestListenerHandleAlive(t *testing.T) {
+	server := raumfeldtest.NewServer(t)
+	server.Break("broken")
+
+	ctx := context.Background()
+	l := NewListener()
+
+	notify := func(usn, nts, location, cacheControl string) {
+		r := &http.Request{Method: "NOTIFY", Header: http.Header{}}
+		r.Header.Set("NT", RaumfeldTypeURN)
+		r.Header.Set("NTS", nts)
+		r.Header.Set("USN", usn)
+		r.Header.Set("LOCATION", location)
+		r.Header.Set("CACHE-CONTROL", cacheControl)
+		l.handle(ctx, r)
+	}
+
+	type event struct {
+		Type SpeakerEventType
+		ID   string
+	}
+
+	for _, tc := range []struct {
+		name   string
+		action func()
+		want   []event
+	}{
+		{
+			name:   "first announcement",
+			action: func() { notify("uuid:kitchen", ntsAlive, server.Location("kitchen", "a.xml"), "max-age=1800") },
+			want:   []event{{SpeakerAdded, "kitchen"}},
+		},
+		{
+			name:   "renewed announcement",
+			action: func() { notify("uuid:kitchen", ntsAlive, server.Location("kitchen", "a.xml"), "max-age=1800") },
+			want:   []event{},
+		},
+		{
+			name:   "changed location",
+			action: func() { notify("uuid:kitchen", ntsUpdate, server.Location("kitchen", "b.xml"), "max-age=1800") },
+			want:   []event{{SpeakerUpdated, "kitchen"}},
+		},
+		{
+			name:   "missing max-age",
+			action: func() { notify("uuid:bath", ntsAlive, server.Location("bath", "a.xml"), "no-cache") },
+			want:   []event{},
+		},
+		{
+			name:   "failed to load",
+			action: func() { notify("uuid:broken", ntsAlive, server.Location("broken", "a.xml"), "max-age=1800") },
+			want:   []event{},
+		},
+		{
+			name:   "goodbye",
+			action: func() { notify("uuid:kitchen", ntsByebye, "", "") },
+			want:   []event{{SpeakerRemoved, "kitchen"}},
+		},
+		{
+			name:   "goodbye of unknown speaker",
+			action: func() { notify("uuid:office", ntsByebye, "", "") },
+			want:   []event{},
+		},
+		{
+			name: "failed reload",
+			action: func() {
+				notify("uuid:garage", ntsAlive, server.Location("garage", "a.xml"), "max-age=1800")
+				server.Break("garage")
+				notify("uuid:garage", ntsUpdate, server.Location("garage", "b.xml"), "max-age=1800")
+			},
+			want: []event{{SpeakerAdded, "garage"}, {SpeakerRemoved, "garage"}},
+		},
+		{
+			name: "short max-age",
+			action: func() {
+				notify("uuid:office", ntsAlive, server.Location("office", "a.xml"), "max-age=60")
+				notify("uuid:bath", ntsAlive, server.Location("bath", "a.xml"), "max-age=1800")
+			},
+			want: []event{{SpeakerAdded, "office"}, {SpeakerAdded, "bath"}},
+		},
+		{
+			name:   "expire",
+			action: func() { l.expireBefore(ctx, time.Now().Add(10*time.Minute)) },
+			want:   []event{{SpeakerRemoved, "office"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.action()
+
+			have := []event{}
+			for len(l.events) > 0 {
+				e := <-l.events
+				have = append(have, event{Type: e.Type, ID: e.ID})
+			}
+			require.Equal(t, tc.want, have)
+		})
+	}
+
+	require.NotContains(t, l.entries, "uuid:broken", "failed speakers must be retried")
+	require.NotContains(t, l.entries, "uuid:garage", "failed speakers must be retried")
+}
@@ -0,0 +1,88 @@
+// Package raumfeldtest contains helpers to test code that works with
+// Raumfeld speakers, without having real speakers around.
+package raumfeldtest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Description is a minimal UPnP device description of a speaker. It needs to
+// be formatted with the speaker ID.
+const Description = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Speaker %[1]s</friendlyName>
+    <UDN>uuid:%[1]s</UDN>
+    <serviceList>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+        <serviceId>urn:upnp-org:serviceId:AVTransport</serviceId>
+        <SCPDURL>/avt.xml</SCPDURL>
+        <controlURL>/avt/control</controlURL>
+        <eventSubURL>/avt/event</eventSubURL>
+      </service>
+      <service>
+        <serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+        <serviceId>urn:upnp-org:serviceId:RenderingControl</serviceId>
+        <SCPDURL>/rc.xml</SCPDURL>
+        <controlURL>/rc/control</controlURL>
+        <eventSubURL>/rc/event</eventSubURL>
+      </service>
+    </serviceList>
+  </device>
+</root>`
+
+// Server serves speaker descriptions at "/<id>/<anything>.xml", so the same
+// speaker can be announced with different locations.
+type Server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	broken map[string]bool
+}
+
+// NewServer starts a description server, which gets closed after the test.
+func NewServer(t testing.TB) *Server {
+	s := &Server{broken: map[string]bool{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	id := strings.Split(strings.Trim(r.URL.Path, "/"), "/")[0]
+
+	s.mu.Lock()
+	broken := s.broken[id]
+	s.mu.Unlock()
+
+	if broken {
+		http.NotFound(w, r)
+		return
+	}
+
+	fmt.Fprintf(w, Description, id)
+}
+
+// Location returns the description URL of the speaker with the given ID. The
+// file name only matters to distinguish locations of the same speaker.
+func (s *Server) Location(id, file string) string {
+	return s.URL + path.Join("/", id, file)
+}
+
+// Break lets all requests for the description of the speaker fail.
+func (s *Server) Break(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.broken[id] = true
+}
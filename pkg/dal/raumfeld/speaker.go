@@ -2,10 +2,13 @@ package raumfeld
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"net"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gosimple/slug"
 	"github.com/huin/goupnp"
@@ -94,7 +97,7 @@ func (s Speaker) Location() *url.URL {
 	return s.location
 }
 
-func (s Speaker) MDNSLocation() (*url.URL, error) {
+func (s Speaker) MDNSLocation(ctx context.Context) (*url.URL, error) {
 	u := *s.location
 
 	addr, port, err := net.SplitHostPort(u.Host)
@@ -102,7 +105,7 @@ func (s Speaker) MDNSLocation() (*url.URL, error) {
 		return nil, fmt.Errorf("split host port of %#v: %w", u.Host, err)
 	}
 
-	hosts, err := net.LookupAddr(addr)
+	hosts, err := net.DefaultResolver.LookupAddr(ctx, addr)
 	if err != nil {
 		return nil, fmt.Errorf("lookup address %#v: %w", addr, err)
 	}
@@ -116,8 +119,8 @@ func (s Speaker) MDNSLocation() (*url.URL, error) {
 	return &u, nil
 }
 
-func (s Speaker) TryMDNSLocation() *url.URL {
-	u, err := s.MDNSLocation()
+func (s Speaker) TryMDNSLocation(ctx context.Context) *url.URL {
+	u, err := s.MDNSLocation(ctx)
 	if err != nil {
 		logrus.Warn(err)
 		return s.location
@@ -130,12 +133,47 @@ func (s Speaker) LocalAddr() net.IP {
 	return s.localAddr
 }
 
+func (s Speaker) Volume(ctx context.Context) (int, error) {
+	return s.ChannelVolume(ctx, ChannelMaster)
+}
+
+// ChannelVolume returns the volume of a single channel, like ChannelLF or
+// ChannelRF.
+func (s Speaker) ChannelVolume(ctx context.Context, channel string) (int, error) {
+	volume, err := s.rc1.GetVolumeCtx(ctx, InstanceID, channel)
+	return int(volume), err
+}
+
+func (s Speaker) Muted(ctx context.Context) (bool, error) {
+	return s.rc1.GetMuteCtx(ctx, InstanceID, ChannelMaster)
+}
+
+func (s Speaker) TransportState(ctx context.Context) (string, error) {
+	state, _, _, err := s.av1.GetTransportInfoCtx(ctx, AVTransportInstanceID)
+	return state, err
+}
+
 func (s Speaker) SetVolumePercent(ctx context.Context, value uint16) error {
-	return s.rc1.SetVolumeCtx(ctx, InstanceID, ChannelMaster, value)
+	return s.SetChannelVolumePercent(ctx, ChannelMaster, value)
 }
 
 func (s Speaker) SetVolumeFloat(ctx context.Context, value float64) error {
-	return s.SetVolumePercent(ctx, uint16(value*100.))
+	return s.SetChannelVolumeFloat(ctx, ChannelMaster, value)
+}
+
+func (s Speaker) SetChannelVolumePercent(ctx context.Context, channel string, value uint16) error {
+	return s.rc1.SetVolumeCtx(ctx, InstanceID, channel, value)
+}
+
+// SetChannelVolumeFloat sets the volume of a channel from a value between 0
+// and 1. Values out of range get clamped.
+func (s Speaker) SetChannelVolumeFloat(ctx context.Context, channel string, value float64) error {
+	if math.IsNaN(value) {
+		return fmt.Errorf("invalid volume %v", value)
+	}
+
+	value = math.Max(0, math.Min(1, value))
+	return s.SetChannelVolumePercent(ctx, channel, uint16(math.Round(value*100.)))
 }
 
 func (s Speaker) SetMute(ctx context.Context, value bool) error {
@@ -161,3 +199,71 @@ func (s Speaker) SetOnOff(ctx context.Context, on bool) error {
 		&request, &response,
 	)
 }
+
+// Transport actions as accepted by SetTransport.
+const (
+	TransportPlay     = "play"
+	TransportPause    = "pause"
+	TransportStop     = "stop"
+	TransportNext     = "next"
+	TransportPrevious = "previous"
+)
+
+// TransportActions lists all actions, that SetTransport accepts.
+var TransportActions = []string{
+	TransportPlay, TransportPause, TransportStop, TransportNext, TransportPrevious,
+}
+
+var ErrUnknownTransportAction = errors.New("unknown transport action")
+
+// SetTransport runs one of the TransportActions on the speaker.
+func (s Speaker) SetTransport(ctx context.Context, action string) error {
+	switch action {
+	case TransportPlay:
+		return s.Play(ctx)
+	case TransportPause:
+		return s.Pause(ctx)
+	case TransportStop:
+		return s.Stop(ctx)
+	case TransportNext:
+		return s.Next(ctx)
+	case TransportPrevious:
+		return s.Previous(ctx)
+	default:
+		return fmt.Errorf("%w %#v", ErrUnknownTransportAction, action)
+	}
+}
+
+func (s Speaker) Play(ctx context.Context) error {
+	return s.av1.PlayCtx(ctx, AVTransportInstanceID, "1")
+}
+
+func (s Speaker) Pause(ctx context.Context) error {
+	return s.av1.PauseCtx(ctx, AVTransportInstanceID)
+}
+
+func (s Speaker) Stop(ctx context.Context) error {
+	return s.av1.StopCtx(ctx, AVTransportInstanceID)
+}
+
+func (s Speaker) Next(ctx context.Context) error {
+	return s.av1.NextCtx(ctx, AVTransportInstanceID)
+}
+
+func (s Speaker) Previous(ctx context.Context) error {
+	return s.av1.PreviousCtx(ctx, AVTransportInstanceID)
+}
+
+// Seek jumps to the given position relative to the start of the current
+// track.
+func (s Speaker) Seek(ctx context.Context, position time.Duration) error {
+	return s.av1.SeekCtx(ctx, AVTransportInstanceID, SeekUnitRelTime, formatDuration(position))
+}
+
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	sec := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%d:%02d:%02d", h, m, sec)
+}
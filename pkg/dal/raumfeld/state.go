@@ -0,0 +1,117 @@
+package raumfeld
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Power states as reported by Raumfeld speakers.
+const (
+	PowerStateActive           = "ACTIVE"
+	PowerStateAutomaticStandby = "AUTOMATIC_STANDBY"
+	PowerStateManualStandby    = "MANUAL_STANDBY"
+)
+
+type State struct {
+	PowerState     string
+	TransportState string
+	Volume         int
+	Muted          bool
+
+	// ChannelVolumes contains the volumes of the ChannelLF and ChannelRF
+	// channels, if the speaker supports them.
+	ChannelVolumes map[string]int
+
+	// Tone is nil, if the speaker does not support tone settings.
+	Tone *Tone
+}
+
+// State actively queries the current state of the speaker. The power state
+// is only available through the LastChange variable, which is not supported
+// by every firmware. It stays empty in this case. The same applies to the
+// tone settings.
+func (s Speaker) State(ctx context.Context) (State, error) {
+	var (
+		state State
+		err   error
+	)
+
+	state.Volume, err = s.Volume(ctx)
+	if err != nil {
+		return State{}, fmt.Errorf("get volume: %w", err)
+	}
+
+	state.Muted, err = s.Muted(ctx)
+	if err != nil {
+		return State{}, fmt.Errorf("get mute: %w", err)
+	}
+
+	state.TransportState, err = s.TransportState(ctx)
+	if err != nil {
+		return State{}, fmt.Errorf("get transport state: %w", err)
+	}
+
+	state.PowerState, err = s.PowerState(ctx)
+	if err != nil {
+		logrus.WithError(err).Debugf("failed to query power state of %#v", s.id)
+	}
+
+	for _, channel := range []string{ChannelLF, ChannelRF} {
+		volume, err := s.ChannelVolume(ctx, channel)
+		if err != nil {
+			logrus.WithError(err).Debugf("failed to query %s volume of %#v", channel, s.id)
+			continue
+		}
+
+		if state.ChannelVolumes == nil {
+			state.ChannelVolumes = map[string]int{}
+		}
+		state.ChannelVolumes[channel] = volume
+	}
+
+	tone, err := s.Tone(ctx)
+	if err != nil {
+		logrus.WithError(err).Debugf("failed to query tone of %#v", s.id)
+	} else {
+		state.Tone = &tone
+	}
+
+	return state, nil
+}
+
+// PowerState reads the power state from the LastChange state variable of
+// the AVTransport service.
+func (s Speaker) PowerState(ctx context.Context) (string, error) {
+	request := struct {
+		VarName string `soap:"varName"`
+	}{
+		VarName: "LastChange",
+	}
+
+	var response struct {
+		Return string `xml:"return"`
+	}
+
+	err := s.av1.SOAPClient.PerformActionCtx(ctx,
+		"urn:schemas-upnp-org:control-1-0", "QueryStateVariable",
+		&request, &response,
+	)
+	if err != nil {
+		return "", fmt.Errorf("query LastChange: %w", err)
+	}
+
+	var event xmlRaumfeldEvent
+	err = xml.Unmarshal([]byte(response.Return), &event)
+	if err != nil {
+		return "", fmt.Errorf("decode LastChange: %w", err)
+	}
+
+	if event.Instance.PowerState == nil {
+		return "", fmt.Errorf("LastChange does not contain a power state")
+	}
+
+	return event.Instance.PowerState.Value, nil
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/sirupsen/logrus"
@@ -16,9 +19,43 @@ func init() {
 	chi.RegisterMethod("NOTIFY")
 }
 
+var subscribedServices = []string{
+	"urn:upnp-org:serviceId:AVTransport",
+	"urn:upnp-org:serviceId:RenderingControl",
+}
+
 type SubscriptionServer struct {
 	handler  SubscribeHandler
 	listener net.Listener
+
+	mu            sync.Mutex
+	subscriptions map[subscriptionKey]*subscription
+}
+
+type subscriptionKey struct {
+	speakerID string
+	service   string
+}
+
+type subscription struct {
+	speaker Speaker
+	service string
+	sid     string
+	expiry  time.Time
+	renewAt time.Time
+
+	seq     uint32
+	seqSeen bool
+}
+
+func (s *subscription) granted(sid string, timeout time.Duration) {
+	now := time.Now()
+	if s.sid != sid {
+		s.seqSeen = false
+	}
+	s.sid = sid
+	s.expiry = now.Add(timeout)
+	s.renewAt = now.Add(timeout / 2)
 }
 
 func NewSubsciptionServer(handler SubscribeHandler) (*SubscriptionServer, error) {
@@ -28,17 +65,24 @@ func NewSubsciptionServer(handler SubscribeHandler) (*SubscriptionServer, error)
 	}
 
 	return &SubscriptionServer{
-		handler:  handler,
-		listener: listener,
+		handler:       handler,
+		listener:      listener,
+		subscriptions: map[subscriptionKey]*subscription{},
 	}, nil
 }
 
-func (s SubscriptionServer) Run(ctx context.Context) error {
+func (s *SubscriptionServer) Run(ctx context.Context) error {
 	r := chi.NewRouter()
 	r.MethodFunc("NOTIFY", "/{id}",
 		func(w http.ResponseWriter, r *http.Request) {
 			speakerID := chi.URLParam(r, "id")
 
+			stale := s.checkSequence(r.Header.Get("SID"), r.Header.Get("SEQ"))
+			if stale {
+				logrus.Warnf("dropping outdated event %s for %#v", r.Header.Get("SEQ"), speakerID)
+				return
+			}
+
 			var root xmlUPNPPropertySet
 			err := xml.NewDecoder(r.Body).Decode(&root)
 			if err != nil {
@@ -58,76 +102,385 @@ func (s SubscriptionServer) Run(ctx context.Context) error {
 					return
 				}
 
-				if event.Instance.Volume != nil {
-					s.handler.OnVolumeChange(speakerID, event.Instance.Volume.Value, event.Instance.Volume.Channel)
+				for _, volume := range event.Instance.Volume {
+					s.handler.OnVolumeChange(speakerID, volume.Value, volume.Channel)
+				}
+
+				for _, mute := range event.Instance.Mute {
+					s.handler.OnMuteChange(speakerID, mute.Value > 0, mute.Channel)
+				}
+
+				if event.Instance.Bass != nil {
+					s.handler.OnBassChange(speakerID, event.Instance.Bass.Value)
+				}
+
+				if event.Instance.Treble != nil {
+					s.handler.OnTrebleChange(speakerID, event.Instance.Treble.Value)
+				}
+
+				if event.Instance.Balance != nil {
+					s.handler.OnBalanceChange(speakerID, event.Instance.Balance.Value)
 				}
 
-				if event.Instance.Mute != nil {
-					muted := event.Instance.Mute.Value > 0
-					s.handler.OnMuteChange(speakerID, muted, event.Instance.Mute.Channel)
+				for _, loudness := range event.Instance.Loudness {
+					s.handler.OnLoudnessChange(speakerID, loudness.Value > 0, loudness.Channel)
 				}
 
 				if event.Instance.PowerState != nil {
 					s.handler.OnPowerStateChange(speakerID, event.Instance.PowerState.Value)
 				}
 
+				if event.Instance.TransportState != nil {
+					s.handler.OnTransportStateChange(speakerID, event.Instance.TransportState.Value)
+				}
+
+				metaData := event.Instance.CurrentTrackMetaData
+				if metaData == nil || metaData.Value == "" {
+					metaData = event.Instance.AVTransportURIMetaData
+				}
+				if metaData != nil {
+					track, err := ParseTrackMetaData(metaData.Value)
+					if err != nil {
+						logrus.WithError(err).Warnf("failed to parse track metadata of %#v", speakerID)
+						continue
+					}
+					s.handler.OnTrackChange(speakerID, track)
+				}
+
 			}
 		})
 	server := new(http.Server)
 	server.Handler = r
 
+	go s.renewLoop(ctx)
+
 	go func() {
 		<-ctx.Done()
+		s.unsubscribeAll()
 		server.Close()
 	}()
 
-	return server.Serve(s.listener)
+	err := server.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Subscribe subscribes to the events of the speaker. Existing subscriptions
+// are kept and renewed in the background, so it is safe to call this
+// regularly.
+func (s *SubscriptionServer) Subscribe(speaker Speaker) error {
+	errs := []error{}
+	for _, service := range subscribedServices {
+		errs = append(errs, s.subscribeService(speaker, service))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SubscriptionServer) subscribeService(speaker Speaker, service string) error {
+	key := subscriptionKey{speakerID: speaker.id, service: service}
+
+	s.mu.Lock()
+	existing := s.subscriptions[key]
+	s.mu.Unlock()
+
+	if existing != nil && existing.speaker.location.String() == speaker.location.String() {
+		return nil
+	}
+
+	if existing != nil {
+		// The speaker moved, therefore the old subscription is useless.
+		s.unsubscribe(existing)
+	}
+
+	sub := &subscription{
+		speaker: speaker,
+		service: service,
+	}
+
+	err := s.subscribe(sub)
+	s.handler.OnSubscriptionStatus(speaker.id, service, err)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.subscriptions[key] = sub
+	s.mu.Unlock()
+
+	return nil
 }
 
-func (s SubscriptionServer) Subscribe(speaker Speaker) error {
-	logrus.Infof("refeshing subscription for %#v", speaker.location.String())
+// Unsubscribe cancels all subscriptions of the speaker with the given ID.
+func (s *SubscriptionServer) Unsubscribe(speakerID string) {
+	s.mu.Lock()
+	subs := []*subscription{}
+	for key, sub := range s.subscriptions {
+		if key.speakerID == speakerID {
+			subs = append(subs, sub)
+			delete(s.subscriptions, key)
+		}
+	}
+	s.mu.Unlock()
 
-	return errors.Join(
-		s.subscribeService(speaker, "urn:upnp-org:serviceId:AVTransport"),
-		s.subscribeService(speaker, "urn:upnp-org:serviceId:RenderingControl"),
-	)
+	for _, sub := range subs {
+		s.unsubscribe(sub)
+	}
 }
 
-func (s SubscriptionServer) subscribeService(speaker Speaker, service string) error {
+func (s *SubscriptionServer) subscribe(sub *subscription) error {
+	logrus.Infof("subscribing to %s of %#v", sub.service, sub.speaker.id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
 	port := s.listener.Addr().(*net.TCPAddr).Port
+	callback := fmt.Sprintf("<http://%s:%d/%s>", sub.speaker.localAddr.String(), port, sub.speaker.id)
+
+	sid, timeout, err := genaSubscribe(ctx, sub.url(), callback)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sub.granted(sid, timeout)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *SubscriptionServer) renew(sub *subscription) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	s.mu.Lock()
+	sid := sub.sid
+	expired := time.Now().After(sub.expiry)
+	s.mu.Unlock()
+
+	if expired {
+		// The speaker already dropped the subscription, so renewing it
+		// would only fail.
+		logrus.Warnf("subscription for %s of %#v expired, resubscribing", sub.service, sub.speaker.id)
+		return s.subscribe(sub)
+	}
+
+	timeout, err := genaRenew(ctx, sub.url(), sid)
+	if errors.Is(err, errSubscriptionGone) {
+		logrus.Warnf("speaker %#v rejected renewal of %s, resubscribing", sub.speaker.id, sub.service)
+		return s.subscribe(sub)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	sub.granted(sid, timeout)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *SubscriptionServer) unsubscribe(sub *subscription) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	subURL := *speaker.location
-	subURL.Path = speaker.eventSubURLs[service]
+	s.mu.Lock()
+	sid := sub.sid
+	s.mu.Unlock()
 
-	r, err := http.NewRequest("SUBSCRIBE", subURL.String(), nil)
+	err := genaUnsubscribe(ctx, sub.url(), sid)
 	if err != nil {
-		return fmt.Errorf("create subscribe request: %w", err)
+		logrus.WithError(err).Warnf("failed to unsubscribe from %s of %#v", sub.service, sub.speaker.id)
 	}
-	r.Header.Set("NT", "upnp:event")
-	r.Header.Set("Callback", fmt.Sprintf("<http://%s:%d/%s>", speaker.localAddr.String(), port, speaker.id))
-	r.Header.Set("Timeout", "Second-1800")
+}
+
+func (s *SubscriptionServer) unsubscribeAll() {
+	s.mu.Lock()
+	subs := s.subscriptions
+	s.subscriptions = map[subscriptionKey]*subscription{}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		s.unsubscribe(sub)
+	}
+}
+
+func (s *SubscriptionServer) renewLoop(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+
+		now := time.Now()
+		due := []*subscription{}
+
+		s.mu.Lock()
+		for _, sub := range s.subscriptions {
+			if now.After(sub.renewAt) {
+				due = append(due, sub)
+			}
+		}
+		s.mu.Unlock()
+
+		for _, sub := range due {
+			err := s.renew(sub)
+			s.handler.OnSubscriptionStatus(sub.speaker.id, sub.service, err)
+			if err != nil {
+				// It gets retried on the next iteration.
+				logrus.WithError(err).Warnf("failed to renew subscription for %s of %#v", sub.service, sub.speaker.id)
+			}
+		}
+	}
+}
 
-	resp, err := http.DefaultClient.Do(r)
+// checkSequence verifies the SEQ header of an event for the subscription with
+// the given SID. It returns true, if the event is older than an already
+// received one and should be dropped. If events got lost in between, it
+// triggers a resync of the speaker.
+func (s *SubscriptionServer) checkSequence(sid, header string) bool {
+	seq, err := strconv.ParseUint(header, 10, 32)
 	if err != nil {
-		return fmt.Errorf("send subscribe request: %w", err)
+		logrus.Warnf("invalid SEQ header %#v for %#v", header, sid)
+		return false
 	}
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status from subscribe: %s", resp.Status)
+
+	s.mu.Lock()
+	var sub *subscription
+	for _, candidate := range s.subscriptions {
+		if candidate.sid == sid {
+			sub = candidate
+			break
+		}
+	}
+	if sub == nil {
+		// The initial event might arrive before the subscribe request
+		// returned and we know the SID.
+		s.mu.Unlock()
+		return false
 	}
+	result := sub.sequence(uint32(seq))
+	s.mu.Unlock()
 
-	return nil
+	switch result {
+	case sequenceStale:
+		return true
+	case sequenceGap:
+		logrus.Warnf("detected missing events for %s of %#v", sub.service, sub.speaker.id)
+		go s.resync(sub.speaker)
+	}
+
+	return false
+}
+
+type sequenceResult int
+
+const (
+	sequenceInOrder sequenceResult = iota
+	sequenceStale
+	sequenceGap
+)
+
+// sequence records the SEQ of a received event. The SEQ starts at 0 for the
+// initial event and wraps around to 1 after reaching the maximum.
+func (s *subscription) sequence(seq uint32) sequenceResult {
+	if !s.seqSeen {
+		s.seq = seq
+		s.seqSeen = true
+		return sequenceInOrder
+	}
+
+	expected := s.seq + 1
+	if expected == 0 {
+		expected = 1
+	}
+
+	switch {
+	case seq == expected:
+		s.seq = seq
+		return sequenceInOrder
+
+	case seq == 0:
+		// The speaker restarted the sequence, so we cannot tell what we
+		// missed.
+		s.seq = seq
+		return sequenceGap
+
+	case int32(seq-s.seq) <= 0:
+		return sequenceStale
+
+	default:
+		s.seq = seq
+		return sequenceGap
+	}
+}
+
+// resync queries the current state of the speaker and passes it to the
+// handler, as if it were received by events.
+func (s *SubscriptionServer) resync(speaker Speaker) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	state, err := speaker.State(ctx)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to resync %#v", speaker.id)
+		return
+	}
+
+	s.handler.OnVolumeChange(speaker.id, state.Volume, ChannelMaster)
+	for channel, volume := range state.ChannelVolumes {
+		s.handler.OnVolumeChange(speaker.id, volume, channel)
+	}
+	s.handler.OnMuteChange(speaker.id, state.Muted, ChannelMaster)
+	s.handler.OnTransportStateChange(speaker.id, state.TransportState)
+	if state.PowerState != "" {
+		s.handler.OnPowerStateChange(speaker.id, state.PowerState)
+	}
+	if state.Tone != nil {
+		s.handler.OnBassChange(speaker.id, state.Tone.Bass)
+		s.handler.OnTrebleChange(speaker.id, state.Tone.Treble)
+		s.handler.OnBalanceChange(speaker.id, state.Tone.Balance)
+		s.handler.OnLoudnessChange(speaker.id, state.Tone.Loudness, ChannelMaster)
+	}
+}
+
+func (s *subscription) url() string {
+	subURL := *s.speaker.location
+	subURL.Path = s.speaker.eventSubURLs[s.service]
+	return subURL.String()
 }
 
 type SubscribeHandler interface {
 	OnVolumeChange(id string, volume int, channel string)
 	OnPowerStateChange(id string, state string)
 	OnMuteChange(id string, muted bool, channel string)
+	OnBassChange(id string, bass int)
+	OnTrebleChange(id string, treble int)
+	OnBalanceChange(id string, balance int)
+	OnLoudnessChange(id string, loudness bool, channel string)
+	OnTransportStateChange(id string, state string)
+	OnTrackChange(id string, track Track)
+
+	// OnSubscriptionStatus gets called after every attempt to subscribe or
+	// renew the subscription of a service. The error is nil, if it
+	// succeeded.
+	OnSubscriptionStatus(id string, service string, err error)
 }
 
 type SubscribeHandlerFuncs struct {
-	VolumeChange     func(id string, volume int, channel string)
-	PowerStateChange func(id string, state string)
-	MuteChange       func(id string, muted bool, channel string)
+	VolumeChange         func(id string, volume int, channel string)
+	PowerStateChange     func(id string, state string)
+	MuteChange           func(id string, muted bool, channel string)
+	BassChange           func(id string, bass int)
+	TrebleChange         func(id string, treble int)
+	BalanceChange        func(id string, balance int)
+	LoudnessChange       func(id string, loudness bool, channel string)
+	TransportStateChange func(id string, state string)
+	TrackChange          func(id string, track Track)
+	SubscriptionStatus   func(id string, service string, err error)
 }
 
 func (h SubscribeHandlerFuncs) OnVolumeChange(id string, volume int, channel string) {
@@ -147,3 +500,45 @@ func (h SubscribeHandlerFuncs) OnMuteChange(id string, muted bool, channel strin
 		h.MuteChange(id, muted, channel)
 	}
 }
+
+func (h SubscribeHandlerFuncs) OnBassChange(id string, bass int) {
+	if h.BassChange != nil {
+		h.BassChange(id, bass)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnTrebleChange(id string, treble int) {
+	if h.TrebleChange != nil {
+		h.TrebleChange(id, treble)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnBalanceChange(id string, balance int) {
+	if h.BalanceChange != nil {
+		h.BalanceChange(id, balance)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnLoudnessChange(id string, loudness bool, channel string) {
+	if h.LoudnessChange != nil {
+		h.LoudnessChange(id, loudness, channel)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnTransportStateChange(id string, state string) {
+	if h.TransportStateChange != nil {
+		h.TransportStateChange(id, state)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnTrackChange(id string, track Track) {
+	if h.TrackChange != nil {
+		h.TrackChange(id, track)
+	}
+}
+
+func (h SubscribeHandlerFuncs) OnSubscriptionStatus(id string, service string, err error) {
+	if h.SubscriptionStatus != nil {
+		h.SubscriptionStatus(id, service, err)
+	}
+}
@@ -0,0 +1,29 @@
+package raumfeld
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriptionSequence(t *testing.T) {
+	sub := new(subscription)
+
+	require.Equal(t, sequenceInOrder, sub.sequence(0))
+	require.Equal(t, sequenceInOrder, sub.sequence(1))
+	require.Equal(t, sequenceStale, sub.sequence(1))
+	require.Equal(t, sequenceGap, sub.sequence(5))
+	require.Equal(t, sequenceStale, sub.sequence(3))
+	require.Equal(t, sequenceInOrder, sub.sequence(6))
+	require.Equal(t, sequenceGap, sub.sequence(0))
+}
+
+func TestSubscriptionSequenceWrap(t *testing.T) {
+	sub := new(subscription)
+
+	require.Equal(t, sequenceInOrder, sub.sequence(math.MaxUint32-1))
+	require.Equal(t, sequenceInOrder, sub.sequence(math.MaxUint32))
+	require.Equal(t, sequenceInOrder, sub.sequence(1))
+	require.Equal(t, sequenceStale, sub.sequence(math.MaxUint32))
+}
@@ -0,0 +1,171 @@
+package raumfeld
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/huin/goupnp/dcps/av1"
+)
+
+// Ranges of the tone settings as accepted by the RenderingControl service.
+const (
+	MinTone    = -10
+	MaxTone    = 10
+	MinBalance = -100
+	MaxBalance = 100
+)
+
+type Tone struct {
+	Bass     int
+	Treble   int
+	Balance  int
+	Loudness bool
+}
+
+// Tone actively queries the tone settings of the speaker.
+func (s Speaker) Tone(ctx context.Context) (Tone, error) {
+	var (
+		tone Tone
+		err  error
+	)
+
+	tone.Bass, err = s.Bass(ctx)
+	if err != nil {
+		return Tone{}, fmt.Errorf("get bass: %w", err)
+	}
+
+	tone.Treble, err = s.Treble(ctx)
+	if err != nil {
+		return Tone{}, fmt.Errorf("get treble: %w", err)
+	}
+
+	tone.Balance, err = s.Balance(ctx)
+	if err != nil {
+		return Tone{}, fmt.Errorf("get balance: %w", err)
+	}
+
+	tone.Loudness, err = s.Loudness(ctx)
+	if err != nil {
+		return Tone{}, fmt.Errorf("get loudness: %w", err)
+	}
+
+	return tone, nil
+}
+
+func (s Speaker) Bass(ctx context.Context) (int, error) {
+	request := struct {
+		InstanceID string
+	}{InstanceID: strconv.Itoa(InstanceID)}
+
+	var response struct {
+		CurrentBass int `xml:"CurrentBass"`
+	}
+
+	err := s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "GetBass",
+		&request, &response,
+	)
+	return response.CurrentBass, err
+}
+
+func (s Speaker) SetBass(ctx context.Context, value int) error {
+	err := validateRange("bass", value, MinTone, MaxTone)
+	if err != nil {
+		return err
+	}
+
+	request := struct {
+		InstanceID  string
+		DesiredBass string
+	}{InstanceID: strconv.Itoa(InstanceID), DesiredBass: strconv.Itoa(value)}
+
+	return s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "SetBass",
+		&request, nil,
+	)
+}
+
+func (s Speaker) Treble(ctx context.Context) (int, error) {
+	request := struct {
+		InstanceID string
+	}{InstanceID: strconv.Itoa(InstanceID)}
+
+	var response struct {
+		CurrentTreble int `xml:"CurrentTreble"`
+	}
+
+	err := s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "GetTreble",
+		&request, &response,
+	)
+	return response.CurrentTreble, err
+}
+
+func (s Speaker) SetTreble(ctx context.Context, value int) error {
+	err := validateRange("treble", value, MinTone, MaxTone)
+	if err != nil {
+		return err
+	}
+
+	request := struct {
+		InstanceID    string
+		DesiredTreble string
+	}{InstanceID: strconv.Itoa(InstanceID), DesiredTreble: strconv.Itoa(value)}
+
+	return s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "SetTreble",
+		&request, nil,
+	)
+}
+
+// Balance returns the balance between the left (negative) and the right
+// (positive) channel.
+func (s Speaker) Balance(ctx context.Context) (int, error) {
+	request := struct {
+		InstanceID string
+	}{InstanceID: strconv.Itoa(InstanceID)}
+
+	var response struct {
+		CurrentBalance int `xml:"CurrentBalance"`
+	}
+
+	err := s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "GetBalance",
+		&request, &response,
+	)
+	return response.CurrentBalance, err
+}
+
+func (s Speaker) SetBalance(ctx context.Context, value int) error {
+	err := validateRange("balance", value, MinBalance, MaxBalance)
+	if err != nil {
+		return err
+	}
+
+	request := struct {
+		InstanceID     string
+		DesiredBalance string
+	}{InstanceID: strconv.Itoa(InstanceID), DesiredBalance: strconv.Itoa(value)}
+
+	return s.rc1.SOAPClient.PerformActionCtx(ctx,
+		av1.URN_RenderingControl_1, "SetBalance",
+		&request, nil,
+	)
+}
+
+func (s Speaker) Loudness(ctx context.Context) (bool, error) {
+	return s.rc1.GetLoudnessCtx(ctx, InstanceID, ChannelMaster)
+}
+
+func (s Speaker) SetLoudness(ctx context.Context, value bool) error {
+	return s.rc1.SetLoudnessCtx(ctx, InstanceID, ChannelMaster, value)
+}
+
+func validateRange(name string, value, min, max int) error {
+	if value < min || value > max {
+		return fmt.Errorf("%s %d is out of range %d:%d", name, value, min, max)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package raumfeld
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type Track struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtURL string
+}
+
+// ParseTrackMetaData decodes the DIDL-Lite document that is used for
+// CurrentTrackMetaData and AVTransportURIMetaData. An empty or
+// "NOT_IMPLEMENTED" payload results in an empty track.
+func ParseTrackMetaData(payload string) (Track, error) {
+	if payload == "" || payload == "NOT_IMPLEMENTED" {
+		return Track{}, nil
+	}
+
+	var didl xmlDIDLLite
+	err := xml.Unmarshal([]byte(payload), &didl)
+	if err != nil {
+		return Track{}, fmt.Errorf("decode DIDL-Lite: %w", err)
+	}
+
+	if len(didl.Items) == 0 {
+		return Track{}, nil
+	}
+
+	item := didl.Items[0]
+	track := Track{
+		Title:       item.Title,
+		Artist:      item.Artist,
+		Album:       item.Album,
+		AlbumArtURL: item.AlbumArtURI,
+	}
+
+	if track.Artist == "" {
+		track.Artist = item.Creator
+	}
+
+	return track, nil
+}
@@ -15,10 +15,19 @@ type xmlRaumfeldEvent struct {
 	Instance xmlRaumfeldInstance `xml:"InstanceID"`
 }
 
+// xmlRaumfeldInstance contains the changed state variables. Variables with a
+// channel occur once per changed channel.
 type xmlRaumfeldInstance struct {
-	Volume     *xmlRaumfeldVolume     `xml:"Volume,omitempty"`
-	Mute       *xmlRaumfeldMute       `xml:"Mute,omitempty"`
-	PowerState *xmlRaumfeldPowerState `xml:"PowerState,omitempty"`
+	Volume                 []xmlRaumfeldVolume    `xml:"Volume,omitempty"`
+	Mute                   []xmlRaumfeldMute      `xml:"Mute,omitempty"`
+	Bass                   *xmlRaumfeldIntValue   `xml:"Bass,omitempty"`
+	Treble                 *xmlRaumfeldIntValue   `xml:"Treble,omitempty"`
+	Balance                *xmlRaumfeldIntValue   `xml:"Balance,omitempty"`
+	Loudness               []xmlRaumfeldLoudness  `xml:"Loudness,omitempty"`
+	PowerState             *xmlRaumfeldPowerState `xml:"PowerState,omitempty"`
+	TransportState         *xmlRaumfeldValue      `xml:"TransportState,omitempty"`
+	CurrentTrackMetaData   *xmlRaumfeldValue      `xml:"CurrentTrackMetaData,omitempty"`
+	AVTransportURIMetaData *xmlRaumfeldValue      `xml:"AVTransportURIMetaData,omitempty"`
 }
 
 type xmlRaumfeldVolume struct {
@@ -31,6 +40,51 @@ type xmlRaumfeldMute struct {
 	Value   int    `xml:"val,attr"`
 }
 
+type xmlRaumfeldLoudness struct {
+	Channel string `xml:"Channel,attr"`
+	Value   int    `xml:"val,attr"`
+}
+
 type xmlRaumfeldPowerState struct {
 	Value string `xml:"val,attr"`
 }
+
+type xmlRaumfeldValue struct {
+	Value string `xml:"val,attr"`
+}
+
+type xmlRaumfeldIntValue struct {
+	Value int `xml:"val,attr"`
+}
+
+// xmlDIDLLite is the metadata format used by UPnP AV. The namespaces are
+// omitted in the tags, since Go matches the local name in that case.
+type xmlDIDLLite struct {
+	XMLName xml.Name          `xml:"DIDL-Lite"`
+	Items   []xmlDIDLLiteItem `xml:"item"`
+}
+
+type xmlDIDLLiteItem struct {
+	Title       string `xml:"title"`
+	Creator     string `xml:"creator"`
+	Artist      string `xml:"artist"`
+	Album       string `xml:"album"`
+	AlbumArtURI string `xml:"albumArtURI"`
+}
+
+type xmlZoneConfig struct {
+	XMLName         xml.Name      `xml:"zoneConfig"`
+	Zones           []xmlZone     `xml:"zones>zone"`
+	UnassignedRooms []xmlZoneRoom `xml:"unassignedRooms>room"`
+}
+
+type xmlZone struct {
+	UDN   string        `xml:"udn,attr"`
+	Rooms []xmlZoneRoom `xml:"room"`
+}
+
+type xmlZoneRoom struct {
+	UDN        string `xml:"udn,attr"`
+	Name       string `xml:"name,attr"`
+	PowerState string `xml:"powerState,attr"`
+}
@@ -13,10 +13,10 @@ import (
 func TestRaumfeldXMLEncode(t *testing.T) {
 	data := xmlRaumfeldEvent{
 		Instance: xmlRaumfeldInstance{
-			Volume: &xmlRaumfeldVolume{
+			Volume: []xmlRaumfeldVolume{{
 				Channel: "Master",
 				Value:   12,
-			},
+			}},
 			PowerState: &xmlRaumfeldPowerState{
 				Value: "ACTIVE",
 			},
@@ -39,9 +39,44 @@ func TestRaumfeldXMLDecodeRCS(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Logf("%#v", have)
-	require.Equal(t, 6, have.Instance.Volume.Value)
-	require.Equal(t, "Master", have.Instance.Volume.Channel)
+	require.Equal(t, []xmlRaumfeldVolume{{Channel: "Master", Value: 6}}, have.Instance.Volume)
+
+}
+
+func TestRaumfeldXMLDecodeTone(t *testing.T) {
+	payload := `<Event xmlns="urn:schemas-upnp-org:metadata-1-0/RCS/"><InstanceID val="0">` +
+		`<Bass val="-3"/><Treble val="2"/><Balance val="10"/><Loudness Channel="Master" val="1"/>` +
+		`</InstanceID></Event>`
+
+	var have xmlRaumfeldEvent
 
+	err := xml.Unmarshal([]byte(payload), &have)
+	require.NoError(t, err)
+
+	require.Equal(t, -3, have.Instance.Bass.Value)
+	require.Equal(t, 2, have.Instance.Treble.Value)
+	require.Equal(t, 10, have.Instance.Balance.Value)
+	require.Equal(t, []xmlRaumfeldLoudness{{Channel: "Master", Value: 1}}, have.Instance.Loudness)
+	require.Empty(t, have.Instance.Volume)
+}
+
+func TestRaumfeldXMLDecodeChannels(t *testing.T) {
+	payload := `<Event xmlns="urn:schemas-upnp-org:metadata-1-0/RCS/"><InstanceID val="0">` +
+		`<Volume Channel="Master" val="30"/><Volume Channel="LF" val="100"/><Volume Channel="RF" val="80"/>` +
+		`<Mute Channel="Master" val="0"/>` +
+		`</InstanceID></Event>`
+
+	var have xmlRaumfeldEvent
+
+	err := xml.Unmarshal([]byte(payload), &have)
+	require.NoError(t, err)
+
+	require.Equal(t, []xmlRaumfeldVolume{
+		{Channel: "Master", Value: 30},
+		{Channel: "LF", Value: 100},
+		{Channel: "RF", Value: 80},
+	}, have.Instance.Volume)
+	require.Equal(t, []xmlRaumfeldMute{{Channel: "Master", Value: 0}}, have.Instance.Mute)
 }
 
 func TestRaumfeldXMLDecodeAVT(t *testing.T) {
@@ -55,3 +90,55 @@ func TestRaumfeldXMLDecodeAVT(t *testing.T) {
 	t.Logf("%#v", have)
 	require.Equal(t, "ACTIVE", have.Instance.PowerState.Value)
 }
+
+func TestRaumfeldXMLDecodeTrack(t *testing.T) {
+	payload := `<Event xmlns="urn:schemas-upnp-org:metadata-1-0/AVT/"><InstanceID val="0">` +
+		`<TransportState val="PLAYING"/>` +
+		`<CurrentTrackMetaData val="&lt;DIDL-Lite xmlns=&quot;urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/&quot; xmlns:dc=&quot;http://purl.org/dc/elements/1.1/&quot; xmlns:upnp=&quot;urn:schemas-upnp-org:metadata-1-0/upnp/&quot;&gt;&lt;item&gt;&lt;dc:title&gt;Bohemian Rhapsody&lt;/dc:title&gt;&lt;upnp:artist&gt;Queen&lt;/upnp:artist&gt;&lt;upnp:album&gt;A Night at the Opera&lt;/upnp:album&gt;&lt;upnp:albumArtURI&gt;http://example.com/cover.jpg&lt;/upnp:albumArtURI&gt;&lt;/item&gt;&lt;/DIDL-Lite&gt;"/>` +
+		`</InstanceID></Event>`
+
+	var have xmlRaumfeldEvent
+
+	err := xml.Unmarshal([]byte(payload), &have)
+	require.NoError(t, err)
+
+	require.Equal(t, "PLAYING", have.Instance.TransportState.Value)
+
+	track, err := ParseTrackMetaData(have.Instance.CurrentTrackMetaData.Value)
+	require.NoError(t, err)
+	require.Equal(t, Track{
+		Title:       "Bohemian Rhapsody",
+		Artist:      "Queen",
+		Album:       "A Night at the Opera",
+		AlbumArtURL: "http://example.com/cover.jpg",
+	}, track)
+}
+
+func TestRaumfeldParseTrackMetaDataEmpty(t *testing.T) {
+	track, err := ParseTrackMetaData("NOT_IMPLEMENTED")
+	require.NoError(t, err)
+	require.Equal(t, Track{}, track)
+}
+
+func TestRaumfeldXMLDecodeZoneConfig(t *testing.T) {
+	payload := `<?xml version="1.0" encoding="utf-8"?>` +
+		`<zoneConfig numRooms="3" updateID="42">` +
+		`<zones><zone udn="uuid:7b6a6fd5-0d7a-4bc0-9b56-4d7b1f3b7a11">` +
+		`<room name="Küche" udn="uuid:cd19c884-dcea-4368-bcb2-fa70d3165631" powerState="ACTIVE"><renderer udn="uuid:a" name="Speaker Küche"/></room>` +
+		`<room name="Wohnzimmer" udn="uuid:0500bb45-f61b-4c44-9565-919a6441c99f" powerState="AUTOMATIC_STANDBY"/>` +
+		`</zone></zones>` +
+		`<unassignedRooms><room name="Bad" udn="uuid:e3b0c442-98fc-1c14-9afb-f4c8996fb924" powerState="MANUAL_STANDBY"/></unassignedRooms>` +
+		`</zoneConfig>`
+
+	var have xmlZoneConfig
+
+	err := xml.Unmarshal([]byte(payload), &have)
+	require.NoError(t, err)
+
+	require.Len(t, have.Zones, 1)
+	require.Len(t, have.Zones[0].Rooms, 2)
+	require.Equal(t, "Küche", have.Zones[0].Rooms[0].Name)
+	require.Equal(t, "AUTOMATIC_STANDBY", have.Zones[0].Rooms[1].PowerState)
+	require.Len(t, have.UnassignedRooms, 1)
+	require.Equal(t, "uuid:e3b0c442-98fc-1c14-9afb-f4c8996fb924", have.UnassignedRooms[0].UDN)
+}
@@ -0,0 +1,192 @@
+package raumfeld
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gosimple/slug"
+	"github.com/huin/goupnp"
+)
+
+// ConfigDeviceURN identifies the Raumfeld host, which manages the rooms and
+// zones of the whole system.
+const ConfigDeviceURN = `urn:schemas-raumfeld-com:device:ConfigDevice:1`
+
+// HostWebServicePort is the port of the web service on the Raumfeld host that
+// provides the zone configuration.
+const HostWebServicePort = "47365"
+
+type ZoneConfig struct {
+	Zones           []Zone
+	UnassignedRooms []Room
+}
+
+type Zone struct {
+	ID    string
+	UDN   string
+	Rooms []Room
+}
+
+type Room struct {
+	ID         string
+	UDN        string
+	Name       string
+	PowerState string
+}
+
+// Rooms returns all rooms, regardless of whether they are assigned to a zone.
+func (c ZoneConfig) Rooms() []Room {
+	result := []Room{}
+	for _, zone := range c.Zones {
+		result = append(result, zone.Rooms...)
+	}
+	return append(result, c.UnassignedRooms...)
+}
+
+// FindRoom looks up a room by its ID, its UDN or its name.
+func (c ZoneConfig) FindRoom(ref string) (Room, bool) {
+	for _, room := range c.Rooms() {
+		if room.ID == ref || room.UDN == ref || strings.EqualFold(room.Name, ref) {
+			return room, true
+		}
+	}
+
+	return Room{}, false
+}
+
+// ZoneOfRoom returns the zone which contains the given room.
+func (c ZoneConfig) ZoneOfRoom(roomID string) (Zone, bool) {
+	for _, zone := range c.Zones {
+		for _, room := range zone.Rooms {
+			if room.ID == roomID {
+				return zone, true
+			}
+		}
+	}
+
+	return Zone{}, false
+}
+
+type Host struct {
+	baseURL *url.URL
+}
+
+// NewHost creates a client for the Raumfeld host web service. The location
+// can be any URL pointing to the host, only its hostname is used.
+func NewHost(location *url.URL) *Host {
+	return &Host{
+		baseURL: &url.URL{
+			Scheme: "http",
+			Host:   net.JoinHostPort(location.Hostname(), HostWebServicePort),
+		},
+	}
+}
+
+func DiscoverHost(ctx context.Context) (*Host, error) {
+	devices, err := goupnp.DiscoverDevicesCtx(ctx, ConfigDeviceURN)
+	if err != nil {
+		return nil, fmt.Errorf("discover host: %w", err)
+	}
+
+	for _, device := range devices {
+		if device.Err != nil {
+			continue
+		}
+
+		return NewHost(device.Location), nil
+	}
+
+	return nil, fmt.Errorf("no raumfeld host found")
+}
+
+func (h *Host) Zones(ctx context.Context) (ZoneConfig, error) {
+	var data xmlZoneConfig
+	err := h.get(ctx, "getZones", nil, &data)
+	if err != nil {
+		return ZoneConfig{}, err
+	}
+
+	result := ZoneConfig{}
+	for _, z := range data.Zones {
+		zone := Zone{
+			ID:  udnToID(z.UDN),
+			UDN: z.UDN,
+		}
+		for _, r := range z.Rooms {
+			zone.Rooms = append(zone.Rooms, r.toRoom())
+		}
+		result.Zones = append(result.Zones, zone)
+	}
+
+	for _, r := range data.UnassignedRooms {
+		result.UnassignedRooms = append(result.UnassignedRooms, r.toRoom())
+	}
+
+	return result, nil
+}
+
+// ConnectRoomsToZone moves the given rooms into the zone. An empty zone UDN
+// creates a new zone.
+func (h *Host) ConnectRoomsToZone(ctx context.Context, zoneUDN string, roomUDNs ...string) error {
+	return h.get(ctx, "connectRoomsToZone", url.Values{
+		"zoneUDN":  []string{zoneUDN},
+		"roomUDNs": []string{strings.Join(roomUDNs, ",")},
+	}, nil)
+}
+
+// DropRoom removes the room from its zone.
+func (h *Host) DropRoom(ctx context.Context, roomUDN string) error {
+	return h.get(ctx, "dropRoomJob", url.Values{
+		"roomUDN": []string{roomUDN},
+	}, nil)
+}
+
+func (h *Host) get(ctx context.Context, endpoint string, query url.Values, result any) error {
+	u := *h.baseURL
+	u.Path = "/" + endpoint
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("create %s request: %w", endpoint, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send %s request: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status from %s: %s", endpoint, resp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	err = xml.NewDecoder(resp.Body).Decode(result)
+	if err != nil {
+		return fmt.Errorf("decode %s response: %w", endpoint, err)
+	}
+
+	return nil
+}
+
+func udnToID(udn string) string {
+	return slug.Make(strings.TrimPrefix(udn, "uuid:"))
+}
+
+func (r xmlZoneRoom) toRoom() Room {
+	return Room{
+		ID:         udnToID(r.UDN),
+		UDN:        r.UDN,
+		Name:       r.Name,
+		PowerState: r.PowerState,
+	}
+}